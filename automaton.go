@@ -0,0 +1,407 @@
+package suffixtree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// A SuffixAutomaton (directed acyclic word graph) is the smallest automaton accepting every
+// suffix of the data.  Each state holds the substrings that end at the same set of offsets,
+// so it is usually much smaller than the tree: at most 2n states and 3n transitions.
+//
+// Like Ukkonen's algorithm it is built online, one value at a time, from the data source's channel.
+// It answers the same queries as a Searcher.  Count and Find derive tables from the automaton the
+// first time they are called after it is extended, so that call should not run concurrently with others.
+type SuffixAutomaton struct {
+	dataChannel <-chan STKey
+	dataSource  DataSource
+	states      []automatonState
+	last        int32 // the state of the whole data read so far
+	length      int32
+
+	// derived when first needed, and dropped when the automaton is extended
+	counts   []int32   // size of each state's set of end offsets
+	children [][]int32 // each state's children in the suffix link tree
+}
+
+type automatonState struct {
+	length   int32 // the longest substring in the state
+	link     int32 // the state of the longest suffix not in this state, -1 for the initial state
+	next     map[STKey]int32
+	firstEnd int32 // offset of the last value of the first occurrence
+	cloned   bool  // created by splitting a state, rather than for a new prefix
+}
+
+func NewSuffixAutomaton(dataSource DataSource) *SuffixAutomaton {
+	return &SuffixAutomaton{
+		dataChannel: dataSource.STKeys(),
+		dataSource:  dataSource,
+		states:      []automatonState{{length: 0, link: -1, next: make(map[STKey]int32), firstEnd: -1}},
+	}
+}
+
+// BuildSuffixAutomaton reads the data source to the end, returning ctx.Err() if the context ends first
+func BuildSuffixAutomaton(ctx context.Context, dataSource DataSource) (*SuffixAutomaton, error) {
+	a := NewSuffixAutomaton(dataSource)
+	for {
+		select {
+		case <-ctx.Done():
+			stopStream(dataSource)
+			return nil, ctx.Err()
+		case value, ok := <-a.dataChannel:
+			if !ok {
+				return a, sourceErr(dataSource)
+			}
+			a.extendValue(value)
+		}
+	}
+}
+
+// Extend adds the next value from the data source, returns false if the data channel is closed
+func (a *SuffixAutomaton) Extend() bool {
+	value, ok := <-a.dataChannel
+	if !ok {
+		return false
+	}
+	a.extendValue(value)
+	return true
+}
+
+func (a *SuffixAutomaton) NumberValuesLoaded() int32 {
+	return a.length
+}
+
+func (a *SuffixAutomaton) NumberStates() int {
+	return len(a.states)
+}
+
+func (a *SuffixAutomaton) DataSource() DataSource {
+	return a.dataSource
+}
+
+func (a *SuffixAutomaton) addState(state automatonState) int32 {
+	a.states = append(a.states, state)
+	return int32(len(a.states) - 1)
+}
+
+func (a *SuffixAutomaton) extendValue(value STKey) {
+	a.counts, a.children = nil, nil
+	current := a.addState(automatonState{length: a.length + 1, next: make(map[STKey]int32), firstEnd: a.length})
+	a.length++
+
+	state := a.last
+	for state >= 0 {
+		if _, ok := a.states[state].next[value]; ok {
+			break
+		}
+		a.states[state].next[value] = current
+		state = a.states[state].link
+	}
+	switch {
+	case state < 0:
+		a.states[current].link = 0
+	case a.states[a.states[state].next[value]].length == a.states[state].length+1:
+		a.states[current].link = a.states[state].next[value]
+	default:
+		// the state reached holds longer substrings too, split off the ones that are now suffixes
+		target := a.states[state].next[value]
+		clone := automatonState{
+			length:   a.states[state].length + 1,
+			link:     a.states[target].link,
+			next:     make(map[STKey]int32, len(a.states[target].next)),
+			firstEnd: a.states[target].firstEnd,
+			cloned:   true,
+		}
+		for key, next := range a.states[target].next {
+			clone.next[key] = next
+		}
+		cloneState := a.addState(clone)
+		for state >= 0 && a.states[state].next[value] == target {
+			a.states[state].next[value] = cloneState
+			state = a.states[state].link
+		}
+		a.states[target].link = cloneState
+		a.states[current].link = cloneState
+	}
+	a.last = current
+}
+
+// the state reached by the sequence, -1 if it is not a substring
+func (a *SuffixAutomaton) walk(sequence []STKey) int32 {
+	state := int32(0)
+	for _, value := range sequence {
+		next, ok := a.states[state].next[value]
+		if !ok {
+			return -1
+		}
+		state = next
+	}
+	return state
+}
+
+// Contains reports whether the sequence occurs in the data
+func (a *SuffixAutomaton) Contains(sequence []STKey) bool {
+	return a.walk(sequence) >= 0
+}
+
+// First returns the offset of the first occurrence of the sequence, -1 if it does not occur
+func (a *SuffixAutomaton) First(sequence []STKey) int32 {
+	state := a.walk(sequence)
+	if state < 0 {
+		return -1
+	}
+	return a.states[state].firstEnd - int32(len(sequence)) + 1
+}
+
+// the suffix link tree, whose leaves are prefix states
+func (a *SuffixAutomaton) linkTree() [][]int32 {
+	if a.children == nil {
+		a.children = make([][]int32, len(a.states))
+		for state := 1; state < len(a.states); state++ {
+			link := a.states[state].link
+			a.children[link] = append(a.children[link], int32(state))
+		}
+	}
+	return a.children
+}
+
+// Count returns the number of occurrences of the sequence
+func (a *SuffixAutomaton) Count(sequence []STKey) (int, error) {
+	state := a.walk(sequence)
+	if state < 0 {
+		return 0, nil
+	}
+	if state == 0 {
+		// the empty sequence, at every offset including the end
+		return int(a.length) + 1, nil
+	}
+	if a.counts == nil {
+		// each prefix state ends one occurrence, its suffixes end there too: sum up the link tree,
+		// longest states first
+		order := make([]int32, len(a.states))
+		for i := range order {
+			order[i] = int32(i)
+		}
+		sort.Slice(order, func(i, j int) bool { return a.states[order[i]].length > a.states[order[j]].length })
+		a.counts = make([]int32, len(a.states))
+		for _, s := range order {
+			if !a.states[s].cloned && s != 0 {
+				a.counts[s]++
+			}
+			if link := a.states[s].link; link >= 0 {
+				a.counts[link] += a.counts[s]
+			}
+		}
+	}
+	return int(a.counts[state]), nil
+}
+
+// Find returns the sorted offsets of every occurrence of the sequence, as Searcher does
+func (a *SuffixAutomaton) Find(sequence []STKey) ([]int32, error) {
+	result := int32arr{}
+	state := a.walk(sequence)
+	if state < 0 {
+		return result, nil
+	}
+	if state == 0 {
+		for offset := int32(0); offset <= a.length; offset++ {
+			result = append(result, offset)
+		}
+		return result, nil
+	}
+	// the occurrences end where the prefixes in the state's link subtree end
+	children := a.linkTree()
+	stack := []int32{state}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !a.states[s].cloned {
+			result = append(result, a.states[s].firstEnd-int32(len(sequence))+1)
+		}
+		stack = append(stack, children[s]...)
+	}
+	sort.Sort(result)
+	return result, nil
+}
+
+// ReversedSuffixTree returns the suffix tree of the data read so far in reverse order, followed
+// by the Terminator, as Ukkonen's algorithm would build it from the reversed data.
+//
+// The automaton's suffix link tree is that suffix tree: each state is a node whose path is the
+// reverse of the state's longest substring.  Prefix states with children in the link tree get
+// a leaf for the Terminator, since their reversed prefix is also the start of longer suffixes.
+// SuffixAutomatonFromReversedTree converts such a tree back.
+func (a *SuffixAutomaton) ReversedSuffixTree() (SuffixTree, error) {
+	n := a.length
+	text := make([]STKey, n+1)
+	for offset := int32(0); offset < n; offset++ {
+		text[n-1-offset] = a.dataSource.KeyAtOffset(offset)
+	}
+	text[n] = Terminator
+
+	factory := NewNodeIdFactory()
+	children := a.linkTree()
+	nodes := make([]Node, len(a.states))
+	nodes[0] = NewRootNode(factory.NextId())
+	// the reversed substring of a state starts where its first occurrence ends
+	stack := []int32{0}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		state := a.states[s]
+		node := nodes[s]
+		if s != 0 {
+			parent := nodes[state.link]
+			start := n - 1 - state.firstEnd + a.states[state.link].length
+			if len(children[s]) == 0 {
+				var edge *Edge
+				edge, node = newLeafForSuffix(factory.NextId(), parent, start, n-state.length)
+				parent.AddOutgoingEdgeNode(text[start], edge, node)
+			} else {
+				edge := NewEdge(start, n-1-state.firstEnd+state.length-1)
+				node = NewInternalNode(factory.NextId(), parent, edge)
+				parent.AddOutgoingEdgeNode(text[start], edge, node)
+			}
+			nodes[s] = node
+		}
+		if (len(children[s]) > 0 || s == 0) && !state.cloned {
+			// the root's Terminator leaf is the empty suffix, other prefix states end a suffix here
+			edge, leaf := newLeafForSuffix(factory.NextId(), node, n, n-state.length)
+			node.AddOutgoingEdgeNode(Terminator, edge, leaf)
+		}
+		stack = append(stack, children[s]...)
+	}
+	root := nodes[0]
+	if err := setSuffixLinks(root, keyText(text)); err != nil {
+		return nil, err
+	}
+	dataSource := NewKeyDataSource(text[:n])
+	linkLeaves(root, dataSource, n)
+	return NewSuffixTree(root, dataSource), nil
+}
+
+// SuffixAutomatonFromReversedTree returns the automaton of a finished tree's data read in reverse
+// order, the converse of ReversedSuffixTree.  The data must not contain the Terminator, since
+// suffixes ending at a '$' in the data have no leaf.
+//
+// Each node is a state, except that a Terminator leaf belongs to its parent, and the tree is the
+// automaton's suffix link tree.  The transitions are the tree's suffix links reversed: a node whose
+// path is a value followed by p is reached on that value from the nodes along p below its parent's depth.
+// The automaton holds all of the data, so Extend has nothing more to add.
+func SuffixAutomatonFromReversedTree(tree SuffixTree) (*SuffixAutomaton, error) {
+	dataSource := tree.DataSource()
+	sized, ok := dataSource.(lengthKnown)
+	if !ok {
+		return nil, errors.New("suffixtree: converting a tree needs a data source with a known length")
+	}
+	n := int32(sized.Len())
+	text := make([]STKey, n)
+	for offset := int32(0); offset < n; offset++ {
+		value, err := keyAt(dataSource, offset)
+		if err != nil {
+			return nil, err
+		}
+		if value == Terminator {
+			return nil, fmt.Errorf("suffixtree: the data contains the Terminator at offset %d", offset)
+		}
+		text[n-1-offset] = value
+	}
+
+	// number the states parents first, a Terminator leaf marks its parent as a prefix state
+	root := tree.Root()
+	stateOf := map[Node]int32{root: 0}
+	depths := map[Node]int32{root: 0}
+	firstValues := make(map[Node]STKey)
+	prefixes := make(map[Node]bool)
+	leaves := make([]Node, n+1)
+	order := []Node{root}
+	for i := 0; i < len(order); i++ {
+		node := order[i]
+		for key, child := range node.outgoingNodeMap() {
+			if !child.IsLeaf() {
+				depths[child] = depths[node] + child.IncomingEdge().length()
+			} else if suffix := child.SuffixOffset(); suffix < 0 || suffix > n || leaves[suffix] != nil {
+				return nil, fmt.Errorf("%w: leaf %d has suffix %d", ErrMalformedTree, child.Id(), suffix)
+			} else {
+				leaves[suffix] = child
+				if child.IncomingEdge().StartOffset == n {
+					prefixes[node] = true
+					continue
+				}
+				depths[child] = n - suffix
+				prefixes[child] = true
+			}
+			stateOf[child] = int32(len(order))
+			firstValues[child] = key
+			if !node.isRoot() {
+				firstValues[child] = firstValues[node]
+			}
+			order = append(order, child)
+		}
+	}
+	stateNode := func(leaf Node) Node {
+		if _, ok := stateOf[leaf]; !ok {
+			return leaf.parent()
+		}
+		return leaf
+	}
+	for suffix, leaf := range leaves {
+		if leaf == nil {
+			return nil, fmt.Errorf("%w: no leaf for suffix %d", ErrMalformedTree, suffix)
+		}
+	}
+
+	a := &SuffixAutomaton{dataSource: NewKeyDataSource(text), states: make([]automatonState, len(order)), length: n}
+	dataChannel := make(chan STKey)
+	close(dataChannel)
+	a.dataChannel = dataChannel
+	lastOffsets := make(map[Node]int32)
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		last := int32(-1)
+		for _, child := range node.OutgoingNodes() {
+			offset, ok := lastOffsets[child]
+			if child.IsLeaf() {
+				offset, ok = child.SuffixOffset(), child.SuffixOffset() < n
+			}
+			if ok && offset > last {
+				last = offset
+			}
+		}
+		if node.IsLeaf() {
+			last = node.SuffixOffset()
+		}
+		lastOffsets[node] = last
+		// the last occurrence in the reversed data is the first in the data
+		a.states[i] = automatonState{length: depths[node], link: -1, next: make(map[STKey]int32),
+			firstEnd: n - 1 - last, cloned: !prefixes[node]}
+		if i == 0 {
+			a.states[i].firstEnd, a.states[i].cloned = -1, false
+		} else {
+			a.states[i].link = stateOf[node.parent()]
+		}
+	}
+
+	for i := 1; i < len(order); i++ {
+		node := order[i]
+		var link Node
+		if node.IsLeaf() {
+			link = stateNode(leaves[node.SuffixOffset()+1])
+		} else {
+			link = node.SuffixLink()
+		}
+		if linkDepth, ok := depths[link]; link == nil || !ok || linkDepth != depths[node]-1 {
+			return nil, fmt.Errorf("%w: node %d has no suffix link one value shallower", ErrMalformedTree, node.Id())
+		}
+		parentDepth := depths[node.parent()]
+		for from := link; from != nil && depths[from] >= parentDepth; from = from.parent() {
+			a.states[stateOf[from]].next[firstValues[node]] = int32(i)
+		}
+	}
+	if n > 0 {
+		a.last = stateOf[stateNode(leaves[0])]
+	}
+	return a, nil
+}
@@ -0,0 +1,76 @@
+package suffixtree
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func reverseString(s string) string {
+	values := []rune(s)
+	for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+		values[i], values[j] = values[j], values[i]
+	}
+	return string(values)
+}
+
+// every sequence of up to maxLength values from the alphabet
+func sequencesUpTo(alphabet string, maxLength int) [][]STKey {
+	sequences := [][]STKey{{}}
+	for start := 0; start < len(sequences); start++ {
+		if len(sequences[start]) == maxLength {
+			continue
+		}
+		for _, r := range alphabet {
+			sequences = append(sequences, append(append([]STKey{}, sequences[start]...), STKey(r)))
+		}
+	}
+	return sequences
+}
+
+// converting a tree to an automaton, and an automaton to a tree, both answer as the automaton
+// built from the data does
+func TestAutomatonTreeConversions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		alphabet := []string{"a", "ab", "abc"}[i%3]
+		s := randomString(r, r.Intn(20), alphabet)
+		built, err := BuildSuffixAutomaton(context.Background(), NewStringDataSource(s))
+		if err != nil {
+			t.Fatalf("building the automaton of %q: %v", s, err)
+		}
+		reversed, err := built.ReversedSuffixTree()
+		if err != nil {
+			t.Fatalf("reversed tree of %q: %v", s, err)
+		}
+		for _, tree := range []SuffixTree{reversed, buildString(t, reverseString(s))} {
+			converted, err := SuffixAutomatonFromReversedTree(tree)
+			if err != nil {
+				t.Fatalf("converting the reversed tree of %q: %v", s, err)
+			}
+			if converted.NumberStates() != built.NumberStates() || converted.NumberValuesLoaded() != built.NumberValuesLoaded() {
+				t.Errorf("%q: converted automaton has %d states for %d values, built has %d for %d", s,
+					converted.NumberStates(), converted.NumberValuesLoaded(), built.NumberStates(), built.NumberValuesLoaded())
+			}
+			for _, sequence := range sequencesUpTo(alphabet, 4) {
+				wantCount, _ := built.Count(sequence)
+				gotCount, _ := converted.Count(sequence)
+				wantFound, _ := built.Find(sequence)
+				gotFound, _ := converted.Find(sequence)
+				if gotCount != wantCount || !reflect.DeepEqual(gotFound, wantFound) || converted.First(sequence) != built.First(sequence) {
+					t.Errorf("%q: %v converted finds %v (%d), built finds %v (%d)", s, sequence, gotFound, gotCount, wantFound, wantCount)
+				}
+			}
+			if converted.Extend() {
+				t.Errorf("%q: the converted automaton extended past the data", s)
+			}
+		}
+	}
+}
+
+func TestAutomatonFromTreeWithTerminator(t *testing.T) {
+	if _, err := SuffixAutomatonFromReversedTree(buildString(t, "ab$ab")); err == nil {
+		t.Error("converted a tree whose data contains the Terminator")
+	}
+}
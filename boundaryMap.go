@@ -67,14 +67,17 @@ func (bm *boundaryMap) IncludesAllSegments(values []int32) bool {
 	return true
 }
 
-func NewBoundaryMap(boundaryName string, suffixTree SuffixTree) BoundaryMap {
+func NewBoundaryMap(boundaryName string, suffixTree SuffixTree) (BoundaryMap, error) {
 	searcher := NewSearcher(suffixTree.Root(), suffixTree.DataSource())
 	findStr := "$" + boundaryName + "$"
 	findSTKey := []STKey{}
 	for _, c := range findStr {
 		findSTKey = append(findSTKey, STKey(c))
 	}
-	result := searcher.Find(findSTKey)
+	result, err := searcher.Find(findSTKey)
+	if err != nil {
+		return nil, err
+	}
 	boundaryMapResult := &boundaryMap{name: boundaryName}
 	nextStartBoundary := int64(1)
 	for _, offset := range result {
@@ -99,5 +102,5 @@ func NewBoundaryMap(boundaryName string, suffixTree SuffixTree) BoundaryMap {
 	}
 
 	boundaryMapResult.Dump(boundaryName)
-	return boundaryMapResult
+	return boundaryMapResult, sourceErr(suffixTree.DataSource())
 }
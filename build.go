@@ -0,0 +1,206 @@
+package suffixtree
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Progress is reported periodically while Build reads the data source
+type Progress struct {
+	ValuesConsumed  int64
+	NodesCreated    int64
+	Elapsed         time.Duration
+	ValuesPerSecond float64       // for file data sources each value is one byte
+	SourceLength    int64         // 0 when the length of the source is unknown
+	ETA             time.Duration // -1 when the length of the source is unknown
+	Done            bool          // set on the final report, after the Terminator is added
+}
+
+// Algorithm selects how Build constructs the tree, every algorithm produces an equivalent tree
+type Algorithm int
+
+const (
+	// Ukkonen's online algorithm, the default, reports progress as values are read
+	UkkonenAlgorithm Algorithm = iota
+	// McCreight's algorithm, reads the whole data source first
+	McCreightAlgorithm
+	// a suffix array (SA-IS) and LCP array are built first, then turned into the tree
+	SuffixArrayAlgorithm
+	// subtrees for partitions of the suffixes are built concurrently, see BuildParallel
+	ParallelAlgorithm
+)
+
+func (algorithm Algorithm) String() string {
+	switch algorithm {
+	case UkkonenAlgorithm:
+		return "ukkonen"
+	case McCreightAlgorithm:
+		return "mccreight"
+	case SuffixArrayAlgorithm:
+		return "suffixarray"
+	case ParallelAlgorithm:
+		return "parallel"
+	}
+	return fmt.Sprintf("Algorithm(%d)", int(algorithm))
+}
+
+// BuildOptions control Build, a nil *BuildOptions builds without progress reports
+type BuildOptions struct {
+	Algorithm Algorithm
+	// used by ParallelAlgorithm
+	Parallel *ParallelOptions
+	// Progress is called from the building goroutine, so it should return quickly
+	Progress func(Progress)
+	// time between Progress calls, defaults to one second
+	ProgressInterval time.Duration
+	// if > 0, Progress is also called after every ProgressEvery values
+	ProgressEvery int64
+	// number of values the data source will provide, used for the ETA.  When 0, data sources
+	// with a Len() int64 method (the string and file data sources) supply it.
+	SourceLength int64
+	// leave the tree implicit, without the Terminator (UkkonenAlgorithm only)
+	NoFinish bool
+	// receives an Event for each construction step (UkkonenAlgorithm only)
+	Tracer Tracer
+}
+
+const defaultProgressInterval = time.Second
+
+// Build reads the data source to the end and returns the finished tree.
+// It returns ctx.Err() if the context is cancelled or its deadline passes first,
+// and the data source's error if the source failed mid-stream.
+func Build(ctx context.Context, dataSource DataSource, opts *BuildOptions) (SuffixTree, error) {
+	if opts == nil {
+		opts = &BuildOptions{}
+	}
+	switch opts.Algorithm {
+	case UkkonenAlgorithm:
+		return buildUkkonen(ctx, dataSource, opts)
+	case McCreightAlgorithm:
+		return buildOffline(ctx, dataSource, opts, func(factory *idFactory, text builderText) (Node, error) {
+			return buildMcCreight(ctx, factory, text)
+		})
+	case SuffixArrayAlgorithm:
+		return buildOffline(ctx, dataSource, opts, buildFromSuffixArray)
+	case ParallelAlgorithm:
+		return buildOffline(ctx, dataSource, opts, func(factory *idFactory, text builderText) (Node, error) {
+			return buildParallel(ctx, factory, text, opts.Parallel)
+		})
+	}
+	return nil, fmt.Errorf("unknown build algorithm %s", opts.Algorithm)
+}
+
+// builders that need the whole text report progress once, when they are done
+func buildOffline(ctx context.Context, dataSource DataSource, opts *BuildOptions,
+	construct func(factory *idFactory, text builderText) (Node, error)) (SuffixTree, error) {
+	start := time.Now()
+	text, err := readTerminatedText(ctx, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	factory := NewNodeIdFactory()
+	root, err := construct(factory, text)
+	if err != nil {
+		return nil, err
+	}
+	linkLeaves(root, dataSource, text.length()-1)
+	if opts.Progress != nil {
+		progress := Progress{
+			ValuesConsumed: int64(text.length() - 1),
+			NodesCreated:   int64(factory._id),
+			Elapsed:        time.Since(start),
+			SourceLength:   int64(text.length() - 1),
+			Done:           true,
+		}
+		if seconds := progress.Elapsed.Seconds(); seconds > 0 {
+			progress.ValuesPerSecond = float64(progress.ValuesConsumed) / seconds
+		}
+		opts.Progress(progress)
+	}
+	return NewSuffixTree(root, dataSource), sourceErr(dataSource)
+}
+
+func buildUkkonen(ctx context.Context, dataSource DataSource, opts *BuildOptions) (SuffixTree, error) {
+	b := NewUkkonen(dataSource).(*ukkonen)
+	b.Trace(opts.Tracer)
+	reporter := newProgressReporter(b, dataSource, opts)
+	if opts.Progress != nil {
+		ticker := time.NewTicker(reporter.interval)
+		defer ticker.Stop()
+		reporter.ticks = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			stopStream(dataSource)
+			return nil, ctx.Err()
+		case <-reporter.ticks:
+			reporter.report(false)
+		case value, ok := <-b.dataChannel:
+			if !ok {
+				if err := sourceErr(dataSource); err != nil {
+					return nil, err
+				}
+				if !opts.NoFinish {
+					b.Finish()
+				}
+				reporter.report(true)
+				return b.Tree(), sourceErr(dataSource)
+			}
+			b.extendValue(value)
+			if opts.ProgressEvery > 0 && int64(b.offset)%opts.ProgressEvery == 0 {
+				reporter.report(false)
+			}
+		}
+	}
+}
+
+type progressReporter struct {
+	builder      *ukkonen
+	callback     func(Progress)
+	interval     time.Duration
+	ticks        <-chan time.Time
+	sourceLength int64
+	start        time.Time
+}
+
+func newProgressReporter(b *ukkonen, dataSource DataSource, opts *BuildOptions) *progressReporter {
+	interval := opts.ProgressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	sourceLength := opts.SourceLength
+	if sized, ok := dataSource.(lengthKnown); ok && sourceLength == 0 {
+		sourceLength = sized.Len()
+	}
+	return &progressReporter{b, opts.Progress, interval, nil, sourceLength, time.Now()}
+}
+
+func (r *progressReporter) report(done bool) {
+	if r.callback == nil {
+		return
+	}
+	progress := Progress{
+		ValuesConsumed: int64(r.builder.NumberValuesLoaded()),
+		NodesCreated:   int64(r.builder.numberNodes()),
+		Elapsed:        time.Since(r.start),
+		SourceLength:   r.sourceLength,
+		ETA:            -1,
+		Done:           done,
+	}
+	if seconds := progress.Elapsed.Seconds(); seconds > 0 {
+		progress.ValuesPerSecond = float64(progress.ValuesConsumed) / seconds
+	}
+	if done {
+		progress.ETA = 0
+	} else if r.sourceLength > 0 && progress.ValuesPerSecond > 0 {
+		remaining := r.sourceLength - progress.ValuesConsumed
+		if remaining < 0 {
+			remaining = 0
+		}
+		progress.ETA = time.Duration(float64(remaining) / progress.ValuesPerSecond * float64(time.Second))
+	}
+	r.callback(progress)
+}
@@ -0,0 +1,108 @@
+package suffixtree
+
+import (
+	"context"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the tree of s built with the given algorithm
+func buildWith(t *testing.T, s string, algorithm Algorithm) SuffixTree {
+	t.Helper()
+	tree, err := Build(context.Background(), NewStringDataSource(s), &BuildOptions{Algorithm: algorithm})
+	if err != nil {
+		t.Fatalf("building %q with %s: %v", s, algorithm, err)
+	}
+	return tree
+}
+
+func randomString(r *rand.Rand, length int, alphabet string) string {
+	values := []rune(alphabet)
+	result := make([]rune, length)
+	for i := range result {
+		result[i] = values[r.Intn(len(values))]
+	}
+	return string(result)
+}
+
+// every builder produces the tree Ukkonen's algorithm does, including for data holding the
+// Terminator and values that do not fit in a byte
+func TestBuildersEquivalent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabets := []string{"a", "ab", "abc", "acgt", "ab$", "a$", "aé€"}
+	builds := []BuildOptions{
+		{Algorithm: McCreightAlgorithm},
+		{Algorithm: SuffixArrayAlgorithm},
+		{Algorithm: ParallelAlgorithm, Parallel: &ParallelOptions{PrefixLength: 1, Workers: 3}},
+		{Algorithm: ParallelAlgorithm, Parallel: &ParallelOptions{PrefixLength: 3, Workers: 2}},
+	}
+	for i := 0; i < 2000; i++ {
+		s := randomString(r, r.Intn(80), alphabets[i%len(alphabets)])
+		ukkonen := buildString(t, s)
+		for _, opts := range builds {
+			opts := opts
+			tree, err := Build(context.Background(), NewStringDataSource(s), &opts)
+			if err != nil {
+				t.Fatalf("%q %s: %v", s, opts.Algorithm, err)
+			}
+			if err := Equivalent(ukkonen, tree); err != nil {
+				t.Fatalf("%q %s: %v", s, opts.Algorithm, err)
+			}
+			if err := Equivalent(tree, ukkonen); err != nil {
+				t.Fatalf("%q %s: %v", s, opts.Algorithm, err)
+			}
+		}
+	}
+}
+
+// wait for the goroutines started since there were before to end
+func waitForGoroutines(t *testing.T, before int, what string) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: %d goroutines after it returned, %d before", what, runtime.NumGoroutine(), before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// the goroutine feeding the data source's channel must not be left blocked on it
+func TestBuildCancelledStopsSource(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Build(ctx, NewStringDataSource("abracadabra"), nil); err != context.Canceled {
+		t.Fatalf("Build with a cancelled context returned %v", err)
+	}
+	waitForGoroutines(t, goroutines, "Build")
+}
+
+func TestDrainDataSourceContext(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	u := NewUkkonen(NewStringDataSource(strings.Repeat("abracadabra", 100)))
+	if err := u.DrainDataSourceContext(ctx); err != context.Canceled {
+		t.Fatalf("DrainDataSourceContext with a cancelled context returned %v", err)
+	}
+	waitForGoroutines(t, goroutines, "DrainDataSourceContext")
+
+	u = NewUkkonen(NewStringDataSource("abracadabra"))
+	if err := u.DrainDataSourceContext(context.Background()); err != nil || u.NumberValuesLoaded() != 11 {
+		t.Fatalf("DrainDataSourceContext loaded %d values, %v", u.NumberValuesLoaded(), err)
+	}
+}
+
+func TestOfflineBuildCancelledStopsSource(t *testing.T) {
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	opts := &BuildOptions{Algorithm: SuffixArrayAlgorithm}
+	if _, err := Build(ctx, NewStringDataSource("abracadabra"), opts); err != context.Canceled {
+		t.Fatalf("Build with a cancelled context returned %v", err)
+	}
+	waitForGoroutines(t, goroutines, "Build")
+}
@@ -0,0 +1,163 @@
+package suffixtree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ErrBadBWT is returned by InverseBWT for a transform without exactly one Terminator
+var ErrBadBWT = errors.New("suffixtree: not a Burrows-Wheeler transform")
+
+// the Terminator sorts before every other value
+func bwtLess(a, b STKey) bool {
+	if a == Terminator || b == Terminator {
+		return a == Terminator && b != Terminator
+	}
+	return a < b
+}
+
+// visit the leaves of a finished tree in the lexicographic order of their suffixes
+func lexicographicLeaves(tree SuffixTree, visit func(offset int32) error) error {
+	stack := []Node{tree.Root()}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node.IsLeaf() {
+			if err := visit(node.SuffixOffset()); err != nil {
+				return err
+			}
+			continue
+		}
+		keys := sortedChildKeys(node)
+		sort.SliceStable(keys, func(i, j int) bool { return bwtLess(keys[i], keys[j]) })
+		children := node.outgoingNodeMap()
+		for i := len(keys) - 1; i >= 0; i-- {
+			child := children[keys[i]]
+			if child == nil {
+				return fmt.Errorf("%w: node %d has a nil child for value %d", ErrMalformedTree, node.Id(), keys[i])
+			}
+			stack = append(stack, child)
+		}
+	}
+	return nil
+}
+
+// visit the values of the transform in order, checking every suffix has its leaf
+func visitBWT(tree SuffixTree, visit func(value STKey) error) error {
+	dataSource := tree.DataSource()
+	leaves, terminators := int64(0), 0
+	err := lexicographicLeaves(tree, func(offset int32) error {
+		leaves++
+		if offset == 0 {
+			terminators++
+			return visit(Terminator)
+		}
+		return visit(dataSource.KeyAtOffset(offset - 1))
+	})
+	if err != nil {
+		return err
+	}
+	if sized, ok := dataSource.(lengthKnown); (ok && leaves != sized.Len()+1) || terminators != 1 {
+		return fmt.Errorf("%w: the transform needs a finished tree over data without the Terminator", ErrMalformedTree)
+	}
+	return sourceErr(dataSource)
+}
+
+// BWT returns the Burrows-Wheeler transform of the tree's data followed by the Terminator: the
+// value before each suffix in sorted order, with the Terminator sorting first.  The tree must be
+// finished, so that every suffix has a leaf.
+func BWT(tree SuffixTree) ([]STKey, error) {
+	result := []STKey{}
+	err := visitBWT(tree, func(value STKey) error {
+		result = append(result, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// write each value as one byte, or as UTF-8
+func writeValue(out *bufio.Writer, value STKey, asBytes bool) error {
+	if asBytes {
+		return out.WriteByte(byte(value))
+	}
+	_, err := out.WriteRune(rune(value))
+	return err
+}
+
+// WriteBWT writes the transform as it walks the tree.  The values of a file data source are
+// written as the bytes they were read from, other values as UTF-8.
+func WriteBWT(w io.Writer, tree SuffixTree) error {
+	out := bufio.NewWriter(w)
+	asBytes := holdsBytes(tree.DataSource())
+	err := visitBWT(tree, func(value STKey) error {
+		return writeValue(out, value, asBytes)
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
+
+// visit the values the transform came from, in order
+func inverseBWT(bwt []STKey, visit func(value STKey) error) error {
+	// next[i] is the row of the suffix one past the suffix in row i, the rows being sorted by
+	// their first value, which is the transform sorted stably
+	next := make([]int32, len(bwt))
+	for i := range next {
+		next[i] = int32(i)
+	}
+	sort.SliceStable(next, func(i, j int) bool { return bwtLess(bwt[next[i]], bwt[next[j]]) })
+
+	// the suffix at offset 0 is the one after the Terminator
+	row := int32(-1)
+	for i, value := range bwt {
+		if value == Terminator {
+			if row >= 0 {
+				return ErrBadBWT
+			}
+			row = int32(i)
+		}
+	}
+	if row < 0 {
+		return ErrBadBWT
+	}
+	for i := 1; i < len(bwt); i++ {
+		if err := visit(bwt[next[row]]); err != nil {
+			return err
+		}
+		row = next[row]
+	}
+	return nil
+}
+
+// InverseBWT returns the values a transform returned by BWT came from, without the Terminator
+func InverseBWT(bwt []STKey) ([]STKey, error) {
+	result := make([]STKey, 0, len(bwt))
+	err := inverseBWT(bwt, func(value STKey) error {
+		result = append(result, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// WriteInverseBWT writes the values a transform came from as they are found, each as one byte
+// if asBytes is set (for the transform of a file's bytes), otherwise as UTF-8
+func WriteInverseBWT(w io.Writer, bwt []STKey, asBytes bool) error {
+	out := bufio.NewWriter(w)
+	err := inverseBWT(bwt, func(value STKey) error {
+		return writeValue(out, value, asBytes)
+	})
+	if err != nil {
+		return err
+	}
+	return out.Flush()
+}
@@ -0,0 +1,72 @@
+package suffixtree
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// a file's bytes go through the writers unchanged, including those that are not ASCII
+func TestWriteBWTBytes(t *testing.T) {
+	data := []byte{}
+	for i := 0; i < 3; i++ {
+		for value := 0; value < 256; value += 1 + i {
+			if STKey(value) != Terminator {
+				data = append(data, byte(value))
+			}
+		}
+	}
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataSource, err := NewFileDataSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := Build(context.Background(), dataSource, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transform, err := BWT(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written bytes.Buffer
+	if err := WriteBWT(&written, tree); err != nil {
+		t.Fatal(err)
+	}
+	if written.Len() != len(transform) {
+		t.Fatalf("wrote %d bytes for a transform of %d values", written.Len(), len(transform))
+	}
+	readBack := make([]STKey, written.Len())
+	for i, b := range written.Bytes() {
+		if readBack[i] = STKey(b); readBack[i] != transform[i] {
+			t.Fatalf("byte %d is %d, the transform has %d", i, b, transform[i])
+		}
+	}
+	var inverse bytes.Buffer
+	if err := WriteInverseBWT(&inverse, readBack, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(inverse.Bytes(), data) {
+		t.Errorf("the inverse of the written transform is not the file's bytes")
+	}
+}
+
+func TestWriteBWTRunes(t *testing.T) {
+	tree := buildString(t, "café")
+	var written, inverse bytes.Buffer
+	if err := WriteBWT(&written, tree); err != nil {
+		t.Fatal(err)
+	}
+	if written.String() != "éc$af" {
+		t.Errorf("WriteBWT wrote %q", written.String())
+	}
+	transform, _ := BWT(tree)
+	if err := WriteInverseBWT(&inverse, transform, false); err != nil || inverse.String() != "café" {
+		t.Errorf("WriteInverseBWT wrote %q, %v", inverse.String(), err)
+	}
+}
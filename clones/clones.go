@@ -0,0 +1,139 @@
+// Package clones finds duplicated Go code, where identifiers and literals may have been renamed
+// consistently, with a parameterized suffix tree.
+//
+//	detector := clones.NewDetector()
+//	detector.AddFile("a.go", src)
+//	detector.AddFile("b.go", other)
+//	found, err := detector.Clones(50)
+//
+// Keywords, operators and punctuation are fixed tokens, identifiers and literals are parameters.
+// Comments are left out, semicolons the scanner inserts at line ends are kept.
+package clones
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"sort"
+
+	"github.com/jojohannsen/suffixtree"
+)
+
+// a separator after each file, so that no clone runs from one file into the next
+const fileSeparator = 1 << 20
+
+// A Token is a scanned token, as a PToken, and where it is
+type Token struct {
+	suffixtree.PToken
+	Pos token.Pos
+}
+
+// A Tokenizer turns Go source into tokens, giving the same name the same parameter in every file
+type Tokenizer struct {
+	fileSet    *token.FileSet
+	parameters map[string]suffixtree.STKey
+}
+
+func NewTokenizer(fileSet *token.FileSet) *Tokenizer {
+	return &Tokenizer{fileSet, make(map[string]suffixtree.STKey)}
+}
+
+// Tokenize scans a file, adding it to the tokenizer's file set
+func (t *Tokenizer) Tokenize(filename string, src []byte) ([]Token, error) {
+	file := t.fileSet.AddFile(filename, -1, len(src))
+	var errors scanner.ErrorList
+	var s scanner.Scanner
+	s.Init(file, src, errors.Add, 0)
+	tokens := []Token{}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		switch tok {
+		case token.IDENT, token.INT, token.FLOAT, token.IMAG, token.CHAR, token.STRING:
+			// a parameter for each identifier, and for each literal of each kind
+			name := tok.String() + " " + lit
+			parameter, ok := t.parameters[name]
+			if !ok {
+				parameter = suffixtree.STKey(len(t.parameters))
+				t.parameters[name] = parameter
+			}
+			tokens = append(tokens, Token{suffixtree.PToken{Value: parameter, Parameter: true}, pos})
+		default:
+			tokens = append(tokens, Token{suffixtree.PToken{Value: suffixtree.STKey(tok)}, pos})
+		}
+	}
+	if errors.Len() > 0 {
+		errors.Sort()
+		return nil, errors.Err()
+	}
+	return tokens, nil
+}
+
+// A Location is a run of tokens in a file, with the lines it starts and ends on
+type Location struct {
+	File      string
+	StartLine int
+	EndLine   int
+}
+
+func (l Location) String() string {
+	return fmt.Sprintf("%s:%d-%d", l.File, l.StartLine, l.EndLine)
+}
+
+// A Clone is two places with the same code, up to renaming, Tokens long
+type Clone struct {
+	A, B   Location
+	Tokens int
+}
+
+// A Detector finds the clones in the files added to it
+type Detector struct {
+	fileSet   *token.FileSet
+	tokenizer *Tokenizer
+	tokens    []Token
+	files     int
+}
+
+func NewDetector() *Detector {
+	fileSet := token.NewFileSet()
+	return &Detector{fileSet: fileSet, tokenizer: NewTokenizer(fileSet)}
+}
+
+// AddFile scans a Go source file, returning the scanner's errors if it is not valid Go
+func (d *Detector) AddFile(filename string, src []byte) error {
+	tokens, err := d.tokenizer.Tokenize(filename, src)
+	if err != nil {
+		return err
+	}
+	d.tokens = append(d.tokens, tokens...)
+	d.tokens = append(d.tokens, Token{suffixtree.PToken{Value: suffixtree.STKey(fileSeparator + d.files)}, token.NoPos})
+	d.files++
+	return nil
+}
+
+func (d *Detector) location(start, length int32) Location {
+	first := d.fileSet.Position(d.tokens[start].Pos)
+	last := d.fileSet.Position(d.tokens[start+length-1].Pos)
+	return Location{first.Filename, first.Line, last.Line}
+}
+
+// Clones returns the clones of at least minTokens tokens in the files added so far, longest first
+func (d *Detector) Clones(minTokens int) ([]Clone, error) {
+	tokens := make([]suffixtree.PToken, len(d.tokens))
+	for i, token := range d.tokens {
+		tokens[i] = token.PToken
+	}
+	tree, err := suffixtree.BuildParameterized(tokens)
+	if err != nil {
+		return nil, err
+	}
+	found := tree.Clones(int32(minTokens))
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Length > found[j].Length })
+	result := make([]Clone, len(found))
+	for i, clone := range found {
+		result[i] = Clone{d.location(clone.A, clone.Length), d.location(clone.B, clone.Length), int(clone.Length)}
+	}
+	return result, nil
+}
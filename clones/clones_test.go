@@ -0,0 +1,92 @@
+package clones
+
+import (
+	"testing"
+)
+
+const sumSource = `package a
+
+func sum(values []int) int {
+	total := 0
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+`
+
+// sum with every identifier renamed and a literal changed
+const renamedSource = `package b
+
+func add(xs []int) int {
+	acc := 1
+	for _, x := range xs {
+		acc += x
+	}
+	return acc
+}
+`
+
+// the same shape, but total is used where value was, which is not a consistent renaming
+const inconsistentSource = `package c
+
+func sum(values []int) int {
+	total := 0
+	for _, value := range values {
+		total += total
+	}
+	return total
+}
+`
+
+func TestDetectorFindsRenamedClone(t *testing.T) {
+	detector := NewDetector()
+	if err := detector.AddFile("a.go", []byte(sumSource)); err != nil {
+		t.Fatal(err)
+	}
+	if err := detector.AddFile("b.go", []byte(renamedSource)); err != nil {
+		t.Fatal(err)
+	}
+	found, err := detector.Clones(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 {
+		t.Fatalf("found %v, want one clone", found)
+	}
+	clone := found[0]
+	if clone.A.File != "a.go" || clone.B.File != "b.go" {
+		t.Fatalf("clone %v is not between a.go and b.go", clone)
+	}
+	// from the package clause to the closing brace
+	if clone.A.StartLine != 1 || clone.A.EndLine != 9 || clone.B.StartLine != 1 || clone.B.EndLine != 9 {
+		t.Fatalf("clone %v, want lines 1 to 9 of both", clone)
+	}
+}
+
+func TestDetectorNeedsConsistentRenaming(t *testing.T) {
+	detector := NewDetector()
+	for _, file := range []struct{ name, src string }{{"a.go", sumSource}, {"c.go", inconsistentSource}} {
+		if err := detector.AddFile(file.name, []byte(file.src)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	found, err := detector.Clones(20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) == 0 {
+		t.Fatal("no clone of the lines before the change")
+	}
+	for _, clone := range found {
+		if clone.A.EndLine > 6 || clone.B.EndLine > 6 {
+			t.Fatalf("clone %v runs past the line that is not a renaming", clone)
+		}
+	}
+}
+
+func TestDetectorRejectsInvalidSource(t *testing.T) {
+	if err := NewDetector().AddFile("bad.go", []byte("package x\nvar s = `unterminated")); err == nil {
+		t.Fatal("no error for invalid source")
+	}
+}
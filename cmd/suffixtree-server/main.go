@@ -0,0 +1,78 @@
+// Command suffixtree-server answers queries against one suffix tree over HTTP, see package server
+// for the endpoints.
+//
+//	suffixtree-server -index book.stx -addr localhost:8080
+//	suffixtree-server first.txt second.txt
+//
+// The tree is loaded from a saved index, or built from the files given: one file is searched
+// byte for byte, several are put in one generalized tree.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/jojohannsen/suffixtree"
+	"github.com/jojohannsen/suffixtree/server"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	index := flag.String("index", "", "saved index to serve, instead of building from files")
+	var opts server.Options
+	flag.DurationVar(&opts.Timeout, "timeout", 0, "longest time a request may take (default 10s)")
+	flag.IntVar(&opts.DefaultLimit, "limit", 0, "results per page when a request has no limit (default 100)")
+	flag.IntVar(&opts.MaxLimit, "max-limit", 0, "most results per page a request may ask for (default 10000)")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: suffixtree-server [flags] [-index file | file...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	tree, err := loadTree(*index, flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("serving on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server.NewHandler(tree, &opts)))
+}
+
+func loadTree(index string, files []string) (suffixtree.SuffixTree, error) {
+	switch {
+	case index != "" && len(files) > 0:
+		return nil, fmt.Errorf("give either -index or files to build from, not both")
+	case index != "":
+		f, err := os.Open(index)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return suffixtree.ReadIndex(f)
+	case len(files) == 1:
+		dataSource, err := suffixtree.NewFileDataSource(files[0])
+		if err != nil {
+			return nil, err
+		}
+		return suffixtree.Build(context.Background(), dataSource, nil)
+	case len(files) > 1:
+		sequences := make([][]suffixtree.STKey, len(files))
+		for i, path := range files {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			sequences[i] = make([]suffixtree.STKey, len(data))
+			for j, b := range data {
+				sequences[i][j] = suffixtree.STKey(b)
+			}
+		}
+		return suffixtree.Build(context.Background(), suffixtree.NewGeneralizedDataSource(sequences...), nil)
+	}
+	flag.Usage()
+	os.Exit(2)
+	return nil, nil
+}
@@ -0,0 +1,543 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jojohannsen/suffixtree"
+	"github.com/jojohannsen/suffixtree/clones"
+)
+
+func runBuild(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("build", "[file]")
+	output := flags.String("o", "", "index file to write (default: the input file with .stx added)")
+	tf.register(flags, false)
+	flags.Parse(args)
+	if err := tf.check(); err != nil {
+		return err
+	}
+	input := "-"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		input = flags.Arg(0)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *output == "" {
+		if input == "-" {
+			return fmt.Errorf("give an index file with -o when reading stdin")
+		}
+		*output = input + ".stx"
+	}
+
+	tree, err := buildTree(input, tf.algorithm)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	if err := suffixtree.WriteIndex(f, tree); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if tf.format == "json" {
+		return writeJSON(map[string]interface{}{"input": input, "index": *output})
+	}
+	fmt.Printf("wrote %s\n", *output)
+	return nil
+}
+
+type patternResult struct {
+	Pattern string  `json:"pattern"`
+	Count   int     `json:"count"`
+	Offsets []int32 `json:"offsets,omitempty"`
+}
+
+// search the tree for every pattern given as an argument
+func searchPatterns(name string, args []string, withOffsets bool) error {
+	var tf treeFlags
+	flags := newFlagSet(name, "pattern...")
+	tf.register(flags, true)
+	limit := 0
+	if withOffsets {
+		flags.IntVar(&limit, "limit", 0, "print at most this many offsets per pattern, 0 for all")
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	tree, err := tf.tree()
+	if err != nil {
+		return err
+	}
+
+	searcher := suffixtree.NewSearcher(tree.Root(), tree.DataSource())
+	results := []patternResult{}
+	for _, pattern := range flags.Args() {
+		offsets, err := searcher.Find(bytesToKeys([]byte(pattern)))
+		if err != nil {
+			return err
+		}
+		result := patternResult{Pattern: pattern, Count: len(offsets)}
+		if withOffsets {
+			if limit > 0 && len(offsets) > limit {
+				offsets = offsets[:limit]
+			}
+			result.Offsets = offsets
+		}
+		results = append(results, result)
+	}
+
+	if tf.format == "json" {
+		return writeJSON(results)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	for _, result := range results {
+		fmt.Fprintf(out, "%q\t%d", result.Pattern, result.Count)
+		for _, offset := range result.Offsets {
+			fmt.Fprintf(out, " %d", offset)
+		}
+		fmt.Fprintln(out)
+	}
+	return out.Flush()
+}
+
+func runFind(args []string) error {
+	return searchPatterns("find", args, true)
+}
+
+func runCount(args []string) error {
+	return searchPatterns("count", args, false)
+}
+
+func runRepeats(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("repeats", "")
+	tf.register(flags, true)
+	minLength := flags.Int("min", 2, "shortest repeat to print")
+	limit := flags.Int("limit", 20, "print at most this many repeats, longest first, 0 for all")
+	flags.Parse(args)
+	tree, err := tf.tree()
+	if err != nil {
+		return err
+	}
+
+	repeats := suffixtree.Repeats(tree, int32(*minLength))
+	if *limit > 0 && len(repeats) > *limit {
+		repeats = repeats[:*limit]
+	}
+	type repeatResult struct {
+		Length  int32   `json:"length"`
+		Count   int     `json:"count"`
+		Text    string  `json:"text"`
+		Offsets []int32 `json:"offsets"`
+	}
+	results := make([]repeatResult, len(repeats))
+	for i, repeat := range repeats {
+		results[i] = repeatResult{repeat.Length, len(repeat.Offsets),
+			textAt(tree.DataSource(), repeat.Offsets[0], repeat.Length), repeat.Offsets}
+	}
+
+	if tf.format == "json" {
+		return writeJSON(results)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	for _, result := range results {
+		fmt.Fprintf(out, "%d\t%d\t%q\n", result.Length, result.Count, result.Text)
+	}
+	return out.Flush()
+}
+
+func runLCS(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("lcs", "file file...")
+	tf.register(flags, false)
+	minFiles := flags.Int("min", 0, "number of files the substring must occur in, 0 for all")
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := tf.check(); err != nil {
+		return err
+	}
+	algorithm, _ := parseAlgorithm(tf.algorithm)
+
+	sequences := make([][]suffixtree.STKey, flags.NArg())
+	for i, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sequences[i] = bytesToKeys(data)
+	}
+	dataSource := suffixtree.NewGeneralizedDataSource(sequences...)
+	tree, err := suffixtree.Build(context.Background(), dataSource, &suffixtree.BuildOptions{Algorithm: algorithm})
+	if err != nil {
+		return err
+	}
+	common, err := suffixtree.LongestCommonSubstring(tree, *minFiles)
+	if err != nil {
+		return err
+	}
+
+	type fileResult struct {
+		File   string `json:"file"`
+		Offset int32  `json:"offset"` // -1 if the substring does not occur in the file
+	}
+	result := struct {
+		Length int32        `json:"length"`
+		Text   string       `json:"text"`
+		Files  []fileResult `json:"files"`
+	}{Length: common.Length}
+	for i, offset := range common.Offsets {
+		result.Files = append(result.Files, fileResult{flags.Arg(i), offset})
+		if offset >= 0 && result.Text == "" {
+			result.Text = textAt(dataSource, dataSource.SequenceStart(i)+offset, common.Length)
+		}
+	}
+
+	if tf.format == "json" {
+		return writeJSON(result)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	fmt.Fprintf(out, "%d\t%q\n", result.Length, result.Text)
+	for _, file := range result.Files {
+		fmt.Fprintf(out, "%s\t%d\n", file.File, file.Offset)
+	}
+	return out.Flush()
+}
+
+func runMUMs(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("mums", "file file")
+	tf.register(flags, false)
+	minLength := flags.Int("min", 20, "shortest match to print")
+	chain := flags.Bool("chain", false, "print only the longest chain of matches in the same order in both files")
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := tf.check(); err != nil {
+		return err
+	}
+	algorithm, _ := parseAlgorithm(tf.algorithm)
+
+	sequences := make([][]suffixtree.STKey, 2)
+	for i, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sequences[i] = bytesToKeys(data)
+	}
+	tree, err := suffixtree.Build(context.Background(), suffixtree.NewGeneralizedDataSource(sequences...),
+		&suffixtree.BuildOptions{Algorithm: algorithm})
+	if err != nil {
+		return err
+	}
+	mums, err := suffixtree.MUMs(tree, int32(*minLength))
+	if err != nil {
+		return err
+	}
+	if *chain {
+		mums = suffixtree.ChainMUMs(mums)
+	}
+
+	type mumResult struct {
+		A      int32 `json:"a"`
+		B      int32 `json:"b"`
+		Length int32 `json:"length"`
+	}
+	results := make([]mumResult, len(mums))
+	for i, mum := range mums {
+		results[i] = mumResult{mum.A, mum.B, mum.Length}
+	}
+	if tf.format == "json" {
+		return writeJSON(results)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	for _, result := range results {
+		fmt.Fprintf(out, "%d\t%d\t%d\n", result.A, result.B, result.Length)
+	}
+	return out.Flush()
+}
+
+func runReuse(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("reuse", "file file...")
+	tf.register(flags, false)
+	minLength := flags.Int("min", 50, "shortest shared passage to report")
+	flags.Parse(args)
+	if flags.NArg() < 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := tf.check(); err != nil {
+		return err
+	}
+	algorithm, _ := parseAlgorithm(tf.algorithm)
+
+	sequences := make([][]suffixtree.STKey, flags.NArg())
+	for i, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sequences[i] = bytesToKeys(data)
+	}
+	dataSource := suffixtree.NewGeneralizedDataSource(sequences...)
+	tree, err := suffixtree.Build(context.Background(), dataSource, &suffixtree.BuildOptions{Algorithm: algorithm})
+	if err != nil {
+		return err
+	}
+	report, err := suffixtree.TextReuse(tree, int32(*minLength))
+	if err != nil {
+		return err
+	}
+
+	if tf.format == "text" {
+		out := bufio.NewWriter(os.Stdout)
+		if err := report.Write(out, flags.Args()); err != nil {
+			return err
+		}
+		return out.Flush()
+	}
+	type passageResult struct {
+		OffsetA int32  `json:"offsetA"`
+		OffsetB int32  `json:"offsetB"`
+		Length  int32  `json:"length"`
+		Text    string `json:"text"`
+	}
+	type pairResult struct {
+		FileA     string          `json:"fileA"`
+		FileB     string          `json:"fileB"`
+		CoverageA float64         `json:"coverageA"`
+		CoverageB float64         `json:"coverageB"`
+		Passages  []passageResult `json:"passages"`
+	}
+	results := []pairResult{}
+	for _, pair := range report.Pairs {
+		result := pairResult{flags.Arg(pair.A), flags.Arg(pair.B), pair.CoverageA, pair.CoverageB, []passageResult{}}
+		for _, passage := range pair.Passages {
+			result.Passages = append(result.Passages, passageResult{passage.OffsetA, passage.OffsetB, passage.Length, passage.Text})
+		}
+		results = append(results, result)
+	}
+	return writeJSON(results)
+}
+
+func runClones(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("clones", "file-or-directory...")
+	tf.register(flags, false)
+	minTokens := flags.Int("min", 50, "fewest tokens in a clone")
+	flags.Parse(args)
+	if flags.NArg() < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err := tf.check(); err != nil {
+		return err
+	}
+
+	detector := clones.NewDetector()
+	for _, root := range flags.Args() {
+		err := filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() || (path != root && filepath.Ext(path) != ".go") {
+				return nil
+			}
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return detector.AddFile(path, src)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	found, err := detector.Clones(*minTokens)
+	if err != nil {
+		return err
+	}
+
+	if tf.format == "json" {
+		type cloneResult struct {
+			A      clones.Location `json:"a"`
+			B      clones.Location `json:"b"`
+			Tokens int             `json:"tokens"`
+		}
+		results := make([]cloneResult, len(found))
+		for i, clone := range found {
+			results[i] = cloneResult{clone.A, clone.B, clone.Tokens}
+		}
+		return writeJSON(results)
+	}
+	out := bufio.NewWriter(os.Stdout)
+	for _, clone := range found {
+		fmt.Fprintf(out, "%s\t%s\t%d\n", clone.A, clone.B, clone.Tokens)
+	}
+	return out.Flush()
+}
+
+func runStats(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("stats", "")
+	tf.register(flags, true)
+	flags.Parse(args)
+	tree, err := tf.tree()
+	if err != nil {
+		return err
+	}
+
+	stats := suffixtree.Stats(tree)
+	if tf.format == "json" {
+		return writeJSON(stats)
+	}
+	return stats.Write(os.Stdout)
+}
+
+func runKmers(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("kmers", "")
+	tf.register(flags, true)
+	k := flags.Int("k", 21, "length of the k-mers")
+	minCount := flags.Int("min", 1, "print only k-mers occurring at least this many times")
+	maxCount := flags.Int("max", 0, "print only k-mers occurring at most this many times, 0 for no limit")
+	spectrum := flags.Bool("spectrum", false, "print how many k-mers occur each number of times instead")
+	midEdge := flags.Bool("midedge", true, "include k-mers ending inside an edge, false for only those followed by more than one value")
+	flags.Parse(args)
+	tree, err := tf.tree()
+	if err != nil {
+		return err
+	}
+	opts := &suffixtree.KmerOptions{MinCount: int32(*minCount), MaxCount: int32(*maxCount), IncludeMidEdge: *midEdge}
+
+	if *spectrum {
+		counts, err := suffixtree.KmerSpectrum(tree, int32(*k), opts)
+		if err != nil {
+			return err
+		}
+		type spectrumResult struct {
+			Count int32 `json:"count"`
+			Kmers int64 `json:"kmers"`
+		}
+		results := []spectrumResult{}
+		for count, kmers := range counts {
+			results = append(results, spectrumResult{count, kmers})
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].Count < results[j].Count })
+		if tf.format == "json" {
+			return writeJSON(results)
+		}
+		out := bufio.NewWriter(os.Stdout)
+		for _, result := range results {
+			fmt.Fprintf(out, "%d\t%d\n", result.Count, result.Kmers)
+		}
+		return out.Flush()
+	}
+
+	type kmerResult struct {
+		Kmer  string `json:"kmer"`
+		Count int32  `json:"count"`
+	}
+	results := []kmerResult{}
+	out := bufio.NewWriter(os.Stdout)
+	err = suffixtree.KmerCounts(tree, int32(*k), opts, func(kmer []suffixtree.STKey, count int32) error {
+		text := make([]byte, len(kmer))
+		for i, value := range kmer {
+			text[i] = byte(value)
+		}
+		if tf.format == "json" {
+			results = append(results, kmerResult{string(text), count})
+			return nil
+		}
+		_, err := fmt.Fprintf(out, "%q\t%d\n", text, count)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if tf.format == "json" {
+		return writeJSON(results)
+	}
+	return out.Flush()
+}
+
+func runDot(args []string) error {
+	var tf treeFlags
+	flags := newFlagSet("dot", "")
+	tf.register(flags, true)
+	var opts suffixtree.ExportOptions
+	flags.IntVar(&opts.MaxDepth, "depth", 0, "leave out nodes more than this many edges below the root, 0 for no limit")
+	flags.IntVar(&opts.MaxLabelLength, "label", 20, "cut edge labels longer than this, 0 for no limit")
+	flags.BoolVar(&opts.SuffixLinks, "links", false, "include suffix links")
+	flags.Parse(args)
+	tree, err := tf.tree()
+	if err != nil {
+		return err
+	}
+	if tf.format == "json" {
+		return suffixtree.WriteJSON(os.Stdout, tree, &opts)
+	}
+	return suffixtree.WriteDOT(os.Stdout, tree, &opts)
+}
+
+func runReplay(args []string) error {
+	flags := newFlagSet("replay", "[file]")
+	dir := flags.String("dot", "", "write the tree after each phase to phase-NNNN.dot files in this directory, instead of a JSON timeline")
+	flags.Parse(args)
+	input := "-"
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		input = flags.Arg(0)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+	var data []byte
+	var err error
+	if input == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(input)
+	}
+	if err != nil {
+		return err
+	}
+
+	recorder := suffixtree.NewRecorder()
+	dataSource := suffixtree.NewKeyDataSource(bytesToKeys(data))
+	if _, err := suffixtree.Build(context.Background(), dataSource, &suffixtree.BuildOptions{Tracer: recorder}); err != nil {
+		return err
+	}
+	if *dir == "" {
+		return recorder.WriteTimeline(os.Stdout)
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return err
+	}
+	return recorder.WriteSnapshots(dataSource, func(phase suffixtree.Phase) (io.WriteCloser, error) {
+		return os.Create(filepath.Join(*dir, fmt.Sprintf("phase-%04d.dot", phase.Offset)))
+	}, nil)
+}
@@ -0,0 +1,187 @@
+// Command suffixtree builds suffix trees from files and queries them.
+//
+//	suffixtree build -o book.stx book.txt
+//	suffixtree find -index book.stx whale ahab
+//	suffixtree repeats -file book.txt -min 20 -format json
+//	suffixtree lcs a.txt b.txt c.txt
+//
+// Every value is one byte of input, patterns given on the command line are matched byte for byte.
+// Query commands read a saved index (-index), or build a tree from a file or stdin (-file).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jojohannsen/suffixtree"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"build", "build a tree from a file or stdin and save it as an index", runBuild},
+		{"find", "print the offsets where each pattern occurs", runFind},
+		{"count", "print the number of occurrences of each pattern", runCount},
+		{"repeats", "print the maximal repeats", runRepeats},
+		{"lcs", "print the longest common substring of several files", runLCS},
+		{"mums", "print the maximal unique matches between two files", runMUMs},
+		{"reuse", "report the passages each pair of files shares", runReuse},
+		{"clones", "print duplicated Go code, allowing renamed identifiers", runClones},
+		{"stats", "print the size and shape of the tree", runStats},
+		{"kmers", "print the k-mers with their counts, or the k-mer spectrum", runKmers},
+		{"dot", "write the tree in Graphviz DOT (or JSON) format", runDot},
+		{"replay", "record each phase of Ukkonen's algorithm as a JSON timeline or DOT files", runReplay},
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: suffixtree <command> [flags] [arguments]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun 'suffixtree <command> -h' for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "suffixtree %s: %v\n", c.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	if os.Args[1] != "-h" && os.Args[1] != "help" {
+		fmt.Fprintf(os.Stderr, "suffixtree: unknown command %q\n", os.Args[1])
+	}
+	usage()
+	os.Exit(2)
+}
+
+// flags shared by the commands that query a tree
+type treeFlags struct {
+	index     string
+	file      string
+	algorithm string
+	format    string
+}
+
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: suffixtree %s [flags] %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func (tf *treeFlags) register(flags *flag.FlagSet, withTree bool) {
+	if withTree {
+		flags.StringVar(&tf.index, "index", "", "saved index to query")
+		flags.StringVar(&tf.file, "file", "", "file to build a tree from instead of an index, - for stdin")
+	}
+	flags.StringVar(&tf.algorithm, "algorithm", "ukkonen", "build algorithm: ukkonen, mccreight, suffixarray or parallel")
+	flags.StringVar(&tf.format, "format", "text", "output format: text or json")
+}
+
+func (tf *treeFlags) check() error {
+	if tf.format != "text" && tf.format != "json" {
+		return fmt.Errorf("unknown format %q", tf.format)
+	}
+	_, err := parseAlgorithm(tf.algorithm)
+	return err
+}
+
+func parseAlgorithm(name string) (suffixtree.Algorithm, error) {
+	for _, algorithm := range []suffixtree.Algorithm{suffixtree.UkkonenAlgorithm, suffixtree.McCreightAlgorithm,
+		suffixtree.SuffixArrayAlgorithm, suffixtree.ParallelAlgorithm} {
+		if algorithm.String() == name {
+			return algorithm, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown algorithm %q", name)
+}
+
+// the tree named by -index or -file
+func (tf *treeFlags) tree() (suffixtree.SuffixTree, error) {
+	if err := tf.check(); err != nil {
+		return nil, err
+	}
+	switch {
+	case tf.index != "" && tf.file != "":
+		return nil, fmt.Errorf("give either -index or -file, not both")
+	case tf.index != "":
+		f, err := os.Open(tf.index)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return suffixtree.ReadIndex(f)
+	case tf.file != "":
+		return buildTree(tf.file, tf.algorithm)
+	}
+	return nil, fmt.Errorf("give a saved index with -index, or a file to build from with -file")
+}
+
+// build a tree from a file, or from stdin when path is "-"
+func buildTree(path, algorithmName string) (suffixtree.SuffixTree, error) {
+	algorithm, err := parseAlgorithm(algorithmName)
+	if err != nil {
+		return nil, err
+	}
+	var dataSource suffixtree.DataSource
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		dataSource = suffixtree.NewKeyDataSource(bytesToKeys(data))
+	} else {
+		dataSource, err = suffixtree.NewFileDataSource(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return suffixtree.Build(context.Background(), dataSource, &suffixtree.BuildOptions{Algorithm: algorithm})
+}
+
+func bytesToKeys(data []byte) []suffixtree.STKey {
+	keys := make([]suffixtree.STKey, len(data))
+	for i, b := range data {
+		keys[i] = suffixtree.STKey(b)
+	}
+	return keys
+}
+
+// the bytes at offset, as a string
+func textAt(dataSource suffixtree.DataSource, offset, length int32) string {
+	var builder strings.Builder
+	for i := int32(0); i < length; i++ {
+		builder.WriteByte(byte(dataSource.KeyAtOffset(offset + i)))
+	}
+	return builder.String()
+}
+
+func writeJSON(value interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
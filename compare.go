@@ -0,0 +1,47 @@
+package suffixtree
+
+import "fmt"
+
+// Equivalent returns nil if the two trees have the same shape, the same edge labels and the
+// same suffix at each leaf.  Node ids, and which occurrence an edge label is read from, may differ.
+func Equivalent(a, b SuffixTree) error {
+	return equivalentNodes(a, b, a.Root(), b.Root(), "")
+}
+
+func equivalentNodes(a, b SuffixTree, nodeA, nodeB Node, path string) error {
+	if nodeA.IsLeaf() != nodeB.IsLeaf() {
+		return fmt.Errorf("at %q: leaf in one tree but not the other", path)
+	}
+	if nodeA.IsLeaf() {
+		if nodeA.SuffixOffset() != nodeB.SuffixOffset() {
+			return fmt.Errorf("at %q: leaf suffixes %d and %d differ", path, nodeA.SuffixOffset(), nodeB.SuffixOffset())
+		}
+		return nil
+	}
+	edgesA, edgesB := nodeA.OutgoingEdgeMap(), nodeB.OutgoingEdgeMap()
+	if len(edgesA) != len(edgesB) {
+		return fmt.Errorf("at %q: %d and %d children", path, len(edgesA), len(edgesB))
+	}
+	for key, edgeA := range edgesA {
+		edgeB := edgesB[key]
+		if edgeB == nil {
+			return fmt.Errorf("at %q: no child for value %d in the second tree", path, key)
+		}
+		childA, childB := nodeA.NodeFollowing(key), nodeB.NodeFollowing(key)
+		label := a.DataSource().StringFrom(edgeA.StartOffset, edgeA.EndOffset)
+		if !childA.IsLeaf() && !childB.IsLeaf() {
+			if edgeA.length() != edgeB.length() {
+				return fmt.Errorf("at %q: edge lengths %d and %d differ", path, edgeA.length(), edgeB.length())
+			}
+			for i := int32(0); i < edgeA.length(); i++ {
+				if a.DataSource().KeyAtOffset(edgeA.StartOffset+i) != b.DataSource().KeyAtOffset(edgeB.StartOffset+i) {
+					return fmt.Errorf("at %q: edge labels %s and %s differ", path, edgeA, edgeB)
+				}
+			}
+		}
+		if err := equivalentNodes(a, b, childA, childB, path+label); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package suffixtree
+
+import "container/heap"
+
+// A Completion is a continuation of a prefix, and how often the prefix is followed by it
+type Completion struct {
+	Values []STKey // not including the prefix or the value that ended it
+	Count  int32
+}
+
+// CompleteOptions control where completions end, a nil *CompleteOptions uses the defaults
+type CompleteOptions struct {
+	// the longest continuation, defaults to 20 values
+	MaxLength int
+	// values that end a continuation, such as a space or a newline.  The end of the data always does,
+	// a '$' in the data only if it is listed.
+	Stop []STKey
+}
+
+const defaultCompletionLength = 20
+
+// A Completer finds the most frequent continuations of prefixes in a finished tree
+type Completer struct {
+	root        Node
+	dataSource  DataSource
+	traverser   Traverser
+	occurrences *occurrences
+	maxLength   int
+	stop        map[STKey]bool
+}
+
+// NewCompleter counts the occurrences below every node of a finished tree, the tree must not change after
+func NewCompleter(tree SuffixTree, opts *CompleteOptions) *Completer {
+	c := &Completer{
+		root:        tree.Root(),
+		dataSource:  tree.DataSource(),
+		traverser:   NewTraverser(tree.DataSource()),
+		occurrences: countOccurrences(tree),
+		maxLength:   defaultCompletionLength,
+		stop:        make(map[STKey]bool),
+	}
+	if opts != nil {
+		if opts.MaxLength > 0 {
+			c.maxLength = opts.MaxLength
+		}
+		for _, value := range opts.Stop {
+			c.stop[value] = true
+		}
+	}
+	return c
+}
+
+// a continuation being extended, or finished
+type completionState struct {
+	location Location
+	values   []STKey
+	count    int32
+	finished bool
+	order    int // ties go to the state pushed first
+}
+
+type completionQueue []*completionState
+
+func (q completionQueue) Len() int { return len(q) }
+func (q completionQueue) Less(i, j int) bool {
+	if q[i].count != q[j].count {
+		return q[i].count > q[j].count
+	}
+	return q[i].order < q[j].order
+}
+func (q completionQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *completionQueue) Push(x interface{}) { *q = append(*q, x.(*completionState)) }
+func (q *completionQueue) Pop() interface{} {
+	old := *q
+	state := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return state
+}
+
+// Complete returns the n most frequent continuations of prefix, most frequent first.
+//
+// The search is best first: a continuation occurs at most as often as any shorter one, so the
+// continuation with the highest count is extended next, and only the branches of the tree that
+// can still reach the n most frequent are visited.
+func (c *Completer) Complete(prefix []STKey, n int) ([]Completion, error) {
+	location := NewLocation(c.root)
+	for _, value := range prefix {
+		found, err := c.traverser.traverseDownValue(location, value)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return []Completion{}, nil
+		}
+	}
+
+	queue := &completionQueue{}
+	pushed := 0
+	push := func(state *completionState) {
+		state.order = pushed
+		pushed++
+		heap.Push(queue, state)
+	}
+	push(&completionState{location: *location, values: []STKey{}, count: c.occurrences.at(location.Base, int32(len(prefix)))})
+	result := []Completion{}
+	for queue.Len() > 0 && len(result) < n {
+		state := heap.Pop(queue).(*completionState)
+		if state.finished {
+			result = append(result, Completion{state.values, state.count})
+			continue
+		}
+		if len(state.values) >= c.maxLength {
+			state.finished = true
+			push(state)
+			continue
+		}
+		// the occurrences that go on past the values so far, the rest end with the data or a stop value
+		depth := int32(len(prefix) + len(state.values))
+		continued := int32(0)
+		if !state.location.OnNode {
+			// one value follows inside an edge, the occurrences that do not end here go on with it
+			offset := state.location.Base.IncomingEdge().StartOffset + state.location.OffsetFromTop
+			if offset < c.occurrences.length {
+				value, err := keyAt(c.dataSource, offset)
+				if err != nil {
+					return nil, err
+				}
+				if !c.stop[value] {
+					next := &completionState{location: state.location, count: c.occurrences.at(state.location.Base, depth+1),
+						values: append(state.values[:len(state.values):len(state.values)], value)}
+					if _, err := c.traverser.traverseDownValue(&next.location, value); err != nil {
+						return nil, err
+					}
+					continued = next.count
+					push(next)
+				}
+			}
+		} else {
+			for _, key := range sortedChildKeys(state.location.Base) {
+				child := state.location.Base.outgoingNodeMap()[key]
+				if c.stop[key] || c.occurrences.terminal(child.IncomingEdge()) {
+					continue
+				}
+				next := &completionState{location: state.location, count: c.occurrences.at(child, depth+1),
+					values: append(state.values[:len(state.values):len(state.values)], key)}
+				if _, err := c.traverser.traverseDownValue(&next.location, key); err != nil {
+					return nil, err
+				}
+				continued += next.count
+				push(next)
+			}
+		}
+		if stopped := state.count - continued; stopped > 0 {
+			push(&completionState{values: state.values, count: stopped, finished: true})
+		}
+	}
+	return result, nil
+}
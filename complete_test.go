@@ -0,0 +1,87 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// the continuations of each occurrence of prefix in s, up to a stop value, the end or maxLength values
+func continuations(s, prefix, stop string, maxLength int) map[string]int32 {
+	counts := make(map[string]int32)
+	text := []rune(s)
+	values := []rune(prefix)
+	for i := 0; i+len(values) <= len(text); i++ {
+		if string(text[i:i+len(values)]) != prefix {
+			continue
+		}
+		end := i + len(values)
+		for end < len(text) && end-i-len(values) < maxLength && !strings.ContainsRune(stop, text[end]) {
+			end++
+		}
+		counts[string(text[i+len(values):end])]++
+	}
+	return counts
+}
+
+func TestCompleteMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		alphabet := []string{"abc ", "ab$", "a$"}[i%3]
+		stop := []string{" ", "", "$"}[i%3]
+		s := randomString(r, r.Intn(30), alphabet)
+		completer := NewCompleter(buildWith(t, s, Algorithm(i%4)), &CompleteOptions{MaxLength: 4, Stop: stringKeys(stop)})
+		for j := 0; j < 10; j++ {
+			prefix := randomString(r, r.Intn(3), alphabet)
+			n := 1 + r.Intn(4)
+			completions, err := completer.Complete(stringKeys(prefix), n)
+			if err != nil {
+				t.Fatalf("Complete(%q) on %q: %v", prefix, s, err)
+			}
+			want := continuations(s, prefix, stop, 4)
+			counts := []int{}
+			for _, count := range want {
+				counts = append(counts, int(count))
+			}
+			sort.Sort(sort.Reverse(sort.IntSlice(counts)))
+			if len(counts) > n {
+				counts = counts[:n]
+			}
+			if len(completions) != len(counts) {
+				t.Fatalf("Complete(%q, %d) on %q = %v, want counts %v", prefix, n, s, completions, counts)
+			}
+			for k, completion := range completions {
+				values := []rune{}
+				for _, value := range completion.Values {
+					values = append(values, rune(value))
+				}
+				if completion.Count != want[string(values)] || int(completion.Count) != counts[k] {
+					t.Fatalf("Complete(%q, %d) on %q = %v, want %v", prefix, n, s, completions, want)
+				}
+			}
+		}
+	}
+}
+
+func TestCompleteDollarInData(t *testing.T) {
+	tree := buildString(t, "abb$abbbb")
+	for _, test := range []struct {
+		stop  string
+		count int32
+	}{{"", 1}, {"$", 2}} {
+		completions, err := NewCompleter(tree, &CompleteOptions{Stop: stringKeys(test.stop)}).Complete(stringKeys("b"), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found := false
+		for _, completion := range completions {
+			if len(completion.Values) == 0 {
+				found = completion.Count == test.count
+			}
+		}
+		if !found {
+			t.Errorf("stopping at %q, Complete(\"b\") = %v, want the empty completion %d times", test.stop, completions, test.count)
+		}
+	}
+}
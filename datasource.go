@@ -2,13 +2,22 @@ package suffixtree
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
 )
 
+// ErrOffsetOutOfRange is recorded when a DataSource is asked for a value it does not have.
+var ErrOffsetOutOfRange = errors.New("suffixtree: offset out of range")
+
 // A DataSource provides a sequence of STKey values over a channel, and allows individual STKey values
 // to be retrieved by their offset.
+//
+// The offset just past the last value holds the Terminator that Ukkonen.Finish appends to the tree.
+// Read errors do not interrupt the caller, the channel is closed early and the data source's Err
+// method, if it has one, reports the first error seen.  The data sources of this package all do.
 type DataSource interface {
 	KeyAtOffset(int32) STKey
 	STKeys() <-chan STKey
@@ -16,20 +25,117 @@ type DataSource interface {
 	StringFromTo(start int32, end string) string
 }
 
+// a DataSource that knows how many values its channel will provide
+type lengthKnown interface {
+	Len() int64
+}
+
+// data sources that remember the first error of a read
+type errorReporter interface {
+	Err() error
+}
+
+// the first error a data source has seen, nil for one that does not report errors
+func sourceErr(dataSource DataSource) error {
+	if reporter, ok := dataSource.(errorReporter); ok {
+		return reporter.Err()
+	}
+	return nil
+}
+
+// data sources that can hand over all their values at once
+type keyLister interface {
+	keys() ([]STKey, error)
+}
+
+// all the values of a data source, up to (not including) the Terminator at offset length
+func keysOf(dataSource DataSource, length int32) ([]STKey, error) {
+	if lister, ok := dataSource.(keyLister); ok {
+		return lister.keys()
+	}
+	keys := make([]STKey, length)
+	for offset := range keys {
+		keys[offset] = dataSource.KeyAtOffset(int32(offset))
+	}
+	return keys, sourceErr(dataSource)
+}
+
+// data sources that can report the error of a single positional read instead of remembering it,
+// so that a query sees only its own errors
+type keyReader interface {
+	keyAt(offset int32) (STKey, error)
+}
+
+// keyAt reads one value, the error is that of this read only
+func keyAt(dataSource DataSource, offset int32) (STKey, error) {
+	if reader, ok := dataSource.(keyReader); ok {
+		return reader.keyAt(offset)
+	}
+	before := sourceErr(dataSource)
+	value := dataSource.KeyAtOffset(offset)
+	if before == nil {
+		return value, sourceErr(dataSource)
+	}
+	return value, nil
+}
+
+// firstError remembers the first error reported by either the goroutine feeding the channel
+// or a positional read
+type firstError struct {
+	mutex sync.Mutex
+	err   error
+}
+
+func (fe *firstError) setErr(err error) {
+	fe.mutex.Lock()
+	defer fe.mutex.Unlock()
+	if fe.err == nil {
+		fe.err = err
+	}
+}
+
+func (fe *firstError) Err() error {
+	fe.mutex.Lock()
+	defer fe.mutex.Unlock()
+	return fe.err
+}
+
+// data sources whose channel can be stopped before it is drained, so that a reader giving up
+// early leaves no goroutine blocked on it
+type streamStopper interface {
+	stopStream()
+}
+
+func stopStream(dataSource DataSource) {
+	if stopper, ok := dataSource.(streamStopper); ok {
+		stopper.stopStream()
+	}
+}
+
+// data sources whose values are bytes rather than runes, such as a file's
+type byteValued interface {
+	byteValues() bool
+}
+
+func holdsBytes(dataSource DataSource) bool {
+	valued, ok := dataSource.(byteValued)
+	return ok && valued.byteValues()
+}
+
 type stringDataSource struct {
-	runes  []rune
-	stream <-chan STKey
+	firstError
+	runes      []rune
+	stream     chan STKey
+	streamOnce sync.Once
+	done       chan struct{}
+	doneOnce   sync.Once
+	stopOnce   sync.Once
 }
 
+// the channel is only fed once someone asks for it, so a data source that is only read
+// by offset (such as one loaded with a saved index) leaves no goroutine behind
 func NewRuneDataSource(runes []rune) DataSource {
-	dataChannel := make(chan STKey)
-	go func(runes []rune, dataChannel chan<- STKey) {
-		for _, r := range runes {
-			dataChannel <- STKey(r)
-		}
-		close(dataChannel)
-	}(runes, dataChannel)
-	return &stringDataSource{runes, dataChannel}
+	return &stringDataSource{runes: runes}
 }
 
 func NewStringDataSource(s string) DataSource {
@@ -37,14 +143,76 @@ func NewStringDataSource(s string) DataSource {
 	return NewRuneDataSource(runes)
 }
 
+// NewKeyDataSource provides values already in memory
+func NewKeyDataSource(keys []STKey) DataSource {
+	runes := make([]rune, len(keys))
+	for i, key := range keys {
+		runes[i] = rune(key)
+	}
+	return NewRuneDataSource(runes)
+}
+
 func (dataSource *stringDataSource) KeyAtOffset(offset int32) STKey {
-	return STKey(dataSource.runes[offset])
+	value, err := dataSource.keyAt(offset)
+	if err != nil {
+		dataSource.setErr(err)
+	}
+	return value
+}
+
+func (dataSource *stringDataSource) keyAt(offset int32) (STKey, error) {
+	if offset >= 0 && int(offset) < len(dataSource.runes) {
+		return STKey(dataSource.runes[offset]), nil
+	}
+	if int(offset) == len(dataSource.runes) {
+		return Terminator, nil
+	}
+	return 0, fmt.Errorf("%w: %d of %d", ErrOffsetOutOfRange, offset, len(dataSource.runes))
+}
+
+func (dataSource *stringDataSource) Len() int64 {
+	return int64(len(dataSource.runes))
 }
 
 func (dataSource *stringDataSource) STKeys() <-chan STKey {
+	dataSource.streamOnce.Do(func() {
+		dataChannel := make(chan STKey)
+		go func(runes []rune, dataChannel chan<- STKey, done <-chan struct{}) {
+			defer close(dataChannel)
+			for _, r := range runes {
+				select {
+				case dataChannel <- STKey(r):
+				case <-done:
+					return
+				}
+			}
+		}(dataSource.runes, dataChannel, dataSource.doneChannel())
+		dataSource.stream = dataChannel
+	})
 	return dataSource.stream
 }
 
+func (dataSource *stringDataSource) doneChannel() chan struct{} {
+	dataSource.doneOnce.Do(func() {
+		dataSource.done = make(chan struct{})
+	})
+	return dataSource.done
+}
+
+func (dataSource *stringDataSource) stopStream() {
+	dataSource.stopOnce.Do(func() {
+		close(dataSource.doneChannel())
+	})
+}
+
+func (dataSource *stringDataSource) keys() ([]STKey, error) {
+	keys := make([]STKey, len(dataSource.runes))
+	for i, r := range dataSource.runes {
+		keys[i] = STKey(r)
+	}
+	return keys, nil
+}
+
 func (s *stringDataSource) StringFrom(start, end int32) string {
 	x := ""
 	if end < 0 {
@@ -60,65 +228,179 @@ func (s *stringDataSource) StringFrom(start, end int32) string {
 	return result
 }
 
+// StringFromTo returns the values from start up to (not including) the first rune of end,
+// or up to the end of the data if that rune does not appear
 func (s *stringDataSource) StringFromTo(start int32, end string) string {
-	return "UNIMPLEMENTED"
+	if start < 0 || int(start) > len(s.runes) {
+		s.setErr(fmt.Errorf("%w: %d of %d", ErrOffsetOutOfRange, start, len(s.runes)))
+		return ""
+	}
+	stop := int(start)
+	endRunes := []rune(end)
+	for stop < len(s.runes) && (len(endRunes) == 0 || s.runes[stop] != endRunes[0]) {
+		stop++
+	}
+	return string(s.runes[start:stop])
 }
 
 type fileDataSource struct {
+	firstError
 	positionalReader *os.File
 	stream           <-chan STKey
-	singleByte       []byte
+	size             int64
+	done             chan struct{}
+	stopOnce         sync.Once
+	closeOnce        sync.Once
+}
+
+func (f *fileDataSource) byteValues() bool {
+	return true
 }
 
+// NewFileDataSource streams the bytes of a file, one STKey per byte.  The returned DataSource
+// also implements io.Closer, closing it releases the file handles.
 func NewFileDataSource(filePath string) (DataSource, error) {
-	dataChannel := make(chan STKey, 1024)
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	sequentialReader := bufio.NewReader(file)
 	positionalReader, err := os.Open(filePath)
-	go func(reader io.Reader, dataChannel chan<- STKey) {
-		var singleByte []byte = []byte{0}
-		var err error
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	info, err := positionalReader.Stat()
+	if err != nil {
+		file.Close()
+		positionalReader.Close()
+		return nil, err
+	}
+	dataChannel := make(chan STKey, 1024)
+	f := &fileDataSource{positionalReader: positionalReader, stream: dataChannel,
+		size: info.Size(), done: make(chan struct{})}
+	go func(file *os.File, dataChannel chan<- STKey) {
+		defer close(dataChannel)
+		defer file.Close()
+		reader := bufio.NewReader(file)
 		for {
-			_, err = reader.Read(singleByte)
+			b, err := reader.ReadByte()
 			if err == io.EOF {
-				break
+				return
+			}
+			if err != nil {
+				f.setErr(fmt.Errorf("reading %s: %w", filePath, err))
+				return
+			}
+			select {
+			case dataChannel <- STKey(b):
+			case <-f.done:
+				return
 			}
-			dataChannel <- STKey(singleByte[0])
 		}
-		close(dataChannel)
-	}(sequentialReader, dataChannel)
-	return &fileDataSource{positionalReader, dataChannel, []byte{0}}, nil
+	}(file, dataChannel)
+	return f, nil
 }
 
 func (f *fileDataSource) KeyAtOffset(offset int32) STKey {
-	f.positionalReader.Seek(int64(offset), os.SEEK_SET)
-	f.positionalReader.Read(f.singleByte)
-	return STKey(f.singleByte[0])
+	value, err := f.keyAt(offset)
+	if err != nil {
+		f.setErr(err)
+	}
+	return value
+}
+
+func (f *fileDataSource) keyAt(offset int32) (STKey, error) {
+	if int64(offset) == f.size {
+		return Terminator, nil
+	}
+	var singleByte [1]byte
+	if _, err := f.positionalReader.ReadAt(singleByte[:], int64(offset)); err != nil {
+		return 0, fmt.Errorf("reading offset %d: %w", offset, err)
+	}
+	return STKey(singleByte[0]), nil
+}
+
+func (f *fileDataSource) Len() int64 {
+	return f.size
 }
 
 func (f *fileDataSource) STKeys() <-chan STKey {
 	return f.stream
 }
 
+func (f *fileDataSource) keys() ([]STKey, error) {
+	byteArray := make([]byte, f.size)
+	if _, err := f.positionalReader.ReadAt(byteArray, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	keys := make([]STKey, len(byteArray))
+	for i, b := range byteArray {
+		keys[i] = STKey(b)
+	}
+	return keys, nil
+}
+
 func (f *fileDataSource) StringFrom(start, end int32) string {
-	var byteArray = make([]byte, end-start+1)
-	f.positionalReader.Seek(int64(start), os.SEEK_SET)
-	f.positionalReader.Read(byteArray)
-	return string(byteArray)
+	x := ""
+	if end < 0 {
+		end = start
+		x = "..."
+	}
+	terminated := int64(end) >= f.size
+	if terminated {
+		end = int32(f.size - 1)
+	}
+	result := ""
+	if end >= start {
+		var byteArray = make([]byte, end-start+1)
+		if _, err := f.positionalReader.ReadAt(byteArray, int64(start)); err != nil {
+			f.setErr(fmt.Errorf("reading offsets %d to %d: %w", start, end, err))
+		}
+		result = string(byteArray)
+	}
+	if terminated {
+		result += string(rune(Terminator))
+	}
+	return result + x
 }
 
+// StringFromTo returns the bytes from start up to (not including) the first byte of end,
+// or up to the end of the file if that byte does not appear
 func (f *fileDataSource) StringFromTo(start int32, end string) string {
 	var byteArray = []byte{}
-	f.positionalReader.Seek(int64(start), os.SEEK_SET)
+	chunk := make([]byte, 512)
+	offset := int64(start)
 	for {
-		f.positionalReader.Read(f.singleByte)
-		if f.singleByte[0] == end[0] {
-			break
+		n, err := f.positionalReader.ReadAt(chunk, offset)
+		for _, b := range chunk[:n] {
+			if len(end) > 0 && b == end[0] {
+				return string(byteArray)
+			}
+			byteArray = append(byteArray, b)
 		}
-		byteArray = append(byteArray, f.singleByte[0])
+		if err == io.EOF {
+			return string(byteArray)
+		}
+		if err != nil {
+			f.setErr(fmt.Errorf("reading from offset %d: %w", offset, err))
+			return string(byteArray)
+		}
+		offset += int64(n)
 	}
-	return string(byteArray)
+}
+
+func (f *fileDataSource) stopStream() {
+	f.stopOnce.Do(func() {
+		close(f.done)
+	})
+}
+
+// Close stops the channel if it has not been drained, and closes the file
+func (f *fileDataSource) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		f.stopStream()
+		err = f.positionalReader.Close()
+	})
+	return err
 }
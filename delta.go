@@ -0,0 +1,218 @@
+package suffixtree
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DeltaKind is what a DeltaInstruction does
+type DeltaKind int
+
+const (
+	// copy Length bytes of the source from SourceOffset
+	DeltaCopy DeltaKind = iota
+	// insert Data, which is not in the source
+	DeltaInsert
+)
+
+func (kind DeltaKind) String() string {
+	switch kind {
+	case DeltaCopy:
+		return "COPY"
+	case DeltaInsert:
+		return "INSERT"
+	}
+	return fmt.Sprintf("DeltaKind(%d)", int(kind))
+}
+
+// A DeltaInstruction writes the next part of the target
+type DeltaInstruction struct {
+	Kind         DeltaKind
+	SourceOffset int32
+	Length       int32
+	Data         []byte
+}
+
+// DeltaOptions control the encoder, a nil *DeltaOptions uses the defaults
+type DeltaOptions struct {
+	// shorter matches are inserted rather than copied, defaults to 8
+	MinCopy int
+}
+
+const defaultMinCopy = 8
+
+// ErrBadDelta is returned by DecodeDelta for a delta EncodeDelta did not write, or written
+// against a different source
+var ErrBadDelta = errors.New("suffixtree: not a valid delta")
+
+const deltaMagic = "STD1"
+
+// DeltaInstructions returns the instructions that build target from the source bytes in the tree,
+// such as a finished tree over NewFileDataSource.
+//
+// At each offset of the target the tree finds the longest match in the source.  Matches of at
+// least MinCopy bytes are copied, anything else is inserted.
+func DeltaInstructions(source SuffixTree, target []byte, opts *DeltaOptions) ([]DeltaInstruction, error) {
+	dataSource := source.DataSource()
+	sized, ok := dataSource.(lengthKnown)
+	if !ok {
+		return nil, errors.New("suffixtree: a delta needs a source with a known length")
+	}
+	n := int32(sized.Len())
+	minCopy := int32(defaultMinCopy)
+	if opts != nil && opts.MinCopy > 0 {
+		minCopy = int32(opts.MinCopy)
+	}
+
+	firstOffset := firstOffsets(source.Root(), n)
+	traverser := NewTraverser(dataSource)
+	// the longest match of the target at start, and the first offset of the source it is at
+	longestMatch := func(start int) (int32, int32, error) {
+		location := NewLocation(source.Root())
+		length, offset := int32(0), int32(0)
+		for _, value := range target[start:] {
+			found, err := traverser.traverseDownValue(location, STKey(value))
+			if err != nil {
+				return 0, 0, err
+			}
+			if !found {
+				break
+			}
+			below := location.Base.SuffixOffset()
+			if !location.Base.IsLeaf() {
+				below = firstOffset[location.Base]
+			}
+			// a '$' in the target matched the Terminator past the end of the source
+			if below+length+1 > n {
+				break
+			}
+			length, offset = length+1, below
+		}
+		return length, offset, nil
+	}
+
+	instructions := []DeltaInstruction{}
+	inserted := 0
+	for start := 0; start < len(target); {
+		length, offset, err := longestMatch(start)
+		if err != nil {
+			return nil, err
+		}
+		if length < minCopy {
+			start++
+			continue
+		}
+		if inserted < start {
+			instructions = append(instructions, DeltaInstruction{Kind: DeltaInsert, Length: int32(start - inserted),
+				Data: target[inserted:start]})
+		}
+		instructions = append(instructions, DeltaInstruction{Kind: DeltaCopy, SourceOffset: offset, Length: length})
+		start += int(length)
+		inserted = start
+	}
+	if inserted < len(target) {
+		instructions = append(instructions, DeltaInstruction{Kind: DeltaInsert, Length: int32(len(target) - inserted),
+			Data: target[inserted:]})
+	}
+	return instructions, sourceErr(dataSource)
+}
+
+// EncodeDelta writes a delta that DecodeDelta turns back into the bytes read from target, given
+// the source bytes in the tree.
+//
+// The format is "STD1", then varints: the lengths of the source and the target, and each
+// instruction's length times 2, plus 1 for an insert.  A copy is followed by the distance from
+// the end of the previous copy to its source offset, an insert by its bytes.
+func EncodeDelta(w io.Writer, source SuffixTree, target io.Reader, opts *DeltaOptions) error {
+	data, err := io.ReadAll(target)
+	if err != nil {
+		return err
+	}
+	instructions, err := DeltaInstructions(source, data, opts)
+	if err != nil {
+		return err
+	}
+
+	out := newVarintWriter(w, deltaMagic)
+	out.uvarint(uint64(source.DataSource().(lengthKnown).Len()))
+	out.uvarint(uint64(len(data)))
+	copied := int64(0)
+	for _, instruction := range instructions {
+		if instruction.Kind == DeltaInsert {
+			out.uvarint(uint64(instruction.Length)<<1 | 1)
+			out.Write(instruction.Data)
+			continue
+		}
+		out.uvarint(uint64(instruction.Length) << 1)
+		out.varint(int64(instruction.SourceOffset) - copied)
+		copied = int64(instruction.SourceOffset) + int64(instruction.Length)
+	}
+	return out.Flush()
+}
+
+// DecodeDelta writes the target a delta was encoded from, reading the same source it was
+// encoded against
+func DecodeDelta(w io.Writer, source io.ReaderAt, sourceLength int64, delta io.Reader) error {
+	in, err := newVarintReader(delta, deltaMagic, ErrBadDelta)
+	if err != nil {
+		return err
+	}
+	encodedLength, err := in.uvarint()
+	if err != nil {
+		return err
+	}
+	if encodedLength != uint64(sourceLength) {
+		return fmt.Errorf("%w: encoded against a source of %d bytes, not %d", ErrBadDelta, encodedLength, sourceLength)
+	}
+	targetLength, err := in.uvarint()
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	written, copied := uint64(0), int64(0)
+	buffer := make([]byte, 32*1024)
+	for written < targetLength {
+		header, err := in.uvarint()
+		if err != nil {
+			return err
+		}
+		length := header >> 1
+		if length == 0 || length > targetLength-written {
+			return ErrBadDelta
+		}
+		if header&1 == 1 {
+			if _, err := io.CopyN(out, in, int64(length)); err != nil {
+				return in.bad(err)
+			}
+		} else {
+			distance, err := in.varint()
+			if err != nil {
+				return err
+			}
+			offset := copied + distance
+			if offset < 0 || offset+int64(length) > sourceLength {
+				return ErrBadDelta
+			}
+			copied = offset + int64(length)
+			for remaining := int64(length); remaining > 0; {
+				chunk := buffer
+				if remaining < int64(len(chunk)) {
+					chunk = chunk[:remaining]
+				}
+				if _, err := source.ReadAt(chunk, offset); err != nil {
+					return err
+				}
+				if _, err := out.Write(chunk); err != nil {
+					return err
+				}
+				offset += int64(len(chunk))
+				remaining -= int64(len(chunk))
+			}
+		}
+		written += length
+	}
+	return out.Flush()
+}
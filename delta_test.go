@@ -0,0 +1,135 @@
+package suffixtree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// the longest run of the source equal to the start of target
+func longestSourceMatch(source, target []byte) int {
+	longest := 0
+	for offset := range source {
+		length := 0
+		for offset+length < len(source) && length < len(target) && source[offset+length] == target[length] {
+			length++
+		}
+		if length > longest {
+			longest = length
+		}
+	}
+	return longest
+}
+
+func fileTree(t *testing.T, data []byte) SuffixTree {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "source")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	dataSource, err := NewFileDataSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dataSource.(interface{ Close() error }).Close() })
+	tree, err := Build(context.Background(), dataSource, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+// a target made of pieces of the source and random bytes
+func editedCopy(r *rand.Rand, source []byte) []byte {
+	target := []byte{}
+	for len(target) < len(source) && r.Intn(20) != 0 {
+		if len(source) > 0 && r.Intn(3) != 0 {
+			start := r.Intn(len(source))
+			end := start + r.Intn(100)
+			if end > len(source) {
+				end = len(source)
+			}
+			target = append(target, source[start:end]...)
+		} else {
+			inserted := make([]byte, r.Intn(20))
+			r.Read(inserted)
+			target = append(target, inserted...)
+		}
+	}
+	return target
+}
+
+// each copy is the longest match in the source at its place in the target, and no insert holds
+// the start of a match long enough to copy
+func TestDeltaInstructions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		source := []byte(randomString(r, r.Intn(300), []string{"ab", "abcd", "ab$"}[i%3]))
+		target := editedCopy(r, source)
+		minCopy := 1 + r.Intn(8)
+		instructions, err := DeltaInstructions(fileTree(t, source), target, &DeltaOptions{MinCopy: minCopy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		built := []byte{}
+		for _, instruction := range instructions {
+			switch instruction.Kind {
+			case DeltaCopy:
+				copied := source[instruction.SourceOffset : instruction.SourceOffset+instruction.Length]
+				if want := longestSourceMatch(source, target[len(built):]); int(instruction.Length) != want || want < minCopy {
+					t.Fatalf("%q to %q: copy of %d at %d, the longest match is %d", source, target, instruction.Length, len(built), want)
+				}
+				built = append(built, copied...)
+			case DeltaInsert:
+				for j := range instruction.Data {
+					if longest := longestSourceMatch(source, target[len(built)+j:]); longest >= minCopy {
+						t.Fatalf("%q to %q: inserted a match of %d at %d", source, target, longest, len(built)+j)
+					}
+				}
+				built = append(built, instruction.Data...)
+			}
+		}
+		if !bytes.Equal(built, target) {
+			t.Fatalf("%q to %q: the instructions build %q", source, target, built)
+		}
+	}
+}
+
+func TestDeltaRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		source := make([]byte, r.Intn(3000))
+		r.Read(source)
+		if i%2 == 0 {
+			for j := range source {
+				source[j] = "abcd$"[source[j]%5]
+			}
+		}
+		target := editedCopy(r, source)
+		var delta, decoded bytes.Buffer
+		if err := EncodeDelta(&delta, fileTree(t, source), bytes.NewReader(target), nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := DecodeDelta(&decoded, bytes.NewReader(source), int64(len(source)), bytes.NewReader(delta.Bytes())); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded.Bytes(), target) {
+			t.Fatalf("source of %d bytes: decoded %d bytes, want %d", len(source), decoded.Len(), len(target))
+		}
+		encoded := delta.Bytes()
+		err := DecodeDelta(&decoded, bytes.NewReader(source), int64(len(source)), bytes.NewReader(encoded[:len(encoded)-1]))
+		if !errors.Is(err, ErrBadDelta) {
+			t.Fatalf("decoding a truncated delta returned %v", err)
+		}
+		if len(source) > 0 {
+			err = DecodeDelta(&decoded, bytes.NewReader(source), int64(len(source)-1), bytes.NewReader(encoded))
+			if !errors.Is(err, ErrBadDelta) {
+				t.Fatalf("decoding against a shorter source returned %v", err)
+			}
+		}
+	}
+}
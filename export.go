@@ -0,0 +1,197 @@
+package suffixtree
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExportOptions control WriteDOT and WriteJSON, a nil *ExportOptions exports the whole tree
+type ExportOptions struct {
+	// nodes more than MaxDepth edges below the root are left out, 0 for no limit
+	MaxDepth int
+	// edge labels longer than this are cut short and end with "...", 0 for no limit
+	MaxLabelLength int
+	// include suffix links (dashed edges in DOT)
+	SuffixLinks bool
+	// DOT only: a label for the whole graph, and ids of nodes to fill in
+	Title     string
+	Highlight []int32
+}
+
+// the keys of a node's children in increasing order
+func sortedChildKeys(node Node) []STKey {
+	keys := make(stkarr, 0, node.NumberOutgoing())
+	for key := range node.OutgoingEdgeMap() {
+		keys = append(keys, key)
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// the values an edge selects.  Leaf edges run to the end of the data, which is only known for
+// data sources with a length, otherwise their label is the first value followed by "...".
+func edgeLabel(dataSource DataSource, edge *Edge, maxLength int) string {
+	start, end := edge.StartOffset, edge.EndOffset
+	if end == FinalOffset {
+		if sized, ok := dataSource.(lengthKnown); ok {
+			end = int32(sized.Len())
+		}
+	}
+	truncated := false
+	if maxLength > 0 && end != FinalOffset && end-start+1 > int32(maxLength) {
+		end = start + int32(maxLength) - 1
+		truncated = true
+	}
+	label := dataSource.StringFrom(start, end)
+	if truncated {
+		label += "..."
+	}
+	return label
+}
+
+type exporter struct {
+	tree SuffixTree
+	opts ExportOptions
+}
+
+func newExporter(tree SuffixTree, opts *ExportOptions) *exporter {
+	e := &exporter{tree: tree}
+	if opts != nil {
+		e.opts = *opts
+	}
+	return e
+}
+
+func (e *exporter) truncatedAt(depth int) bool {
+	return e.opts.MaxDepth > 0 && depth >= e.opts.MaxDepth
+}
+
+// WriteDOT writes the tree in Graphviz DOT format: edges are labelled with their values,
+// leaves with their suffix offsets.
+func WriteDOT(w io.Writer, tree SuffixTree, opts *ExportOptions) error {
+	e := newExporter(tree, opts)
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "digraph suffixtree {")
+	fmt.Fprintln(out, "  node [shape=circle, label=\"\", width=0.2];")
+	if e.opts.Title != "" {
+		fmt.Fprintf(out, "  label=\"%s\";\n  labelloc=t;\n", dotEscape(e.opts.Title))
+	}
+	highlighted := make(map[int32]bool)
+	for _, id := range e.opts.Highlight {
+		highlighted[id] = true
+	}
+	included := make(map[Node]bool)
+	var writeNode func(node Node, depth int)
+	writeNode = func(node Node, depth int) {
+		included[node] = true
+		if highlighted[node.Id()] {
+			fmt.Fprintf(out, "  n%d [style=filled, fillcolor=lightblue];\n", node.Id())
+		}
+		switch {
+		case node.isRoot():
+			fmt.Fprintf(out, "  n%d [shape=doublecircle];\n", node.Id())
+		case node.IsLeaf():
+			fmt.Fprintf(out, "  n%d [shape=box, label=\"%d\"];\n", node.Id(), node.SuffixOffset())
+		case e.truncatedAt(depth):
+			fmt.Fprintf(out, "  n%d [shape=plaintext, label=\"...\"];\n", node.Id())
+		}
+		if e.truncatedAt(depth) {
+			return
+		}
+		for _, key := range sortedChildKeys(node) {
+			edge, child := node.outgoingEdgeNode(key)
+			writeNode(child, depth+1)
+			fmt.Fprintf(out, "  n%d -> n%d [label=\"%s\"];\n", node.Id(), child.Id(),
+				dotEscape(edgeLabel(tree.DataSource(), edge, e.opts.MaxLabelLength)))
+		}
+	}
+	writeNode(tree.Root(), 0)
+	if e.opts.SuffixLinks {
+		nodes := make([]Node, 0, len(included))
+		for node := range included {
+			nodes = append(nodes, node)
+		}
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].Id() < nodes[j].Id() })
+		for _, node := range nodes {
+			if link := node.SuffixLink(); link != nil && link != node && included[link] {
+				fmt.Fprintf(out, "  n%d -> n%d [style=dashed, color=gray, constraint=false];\n", node.Id(), link.Id())
+			}
+		}
+	}
+	fmt.Fprintln(out, "}")
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return sourceErr(tree.DataSource())
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func dotEscape(s string) string {
+	return dotEscaper.Replace(s)
+}
+
+// JSON documents written by WriteJSON
+type jsonTree struct {
+	Root *jsonNode `json:"root"`
+}
+
+type jsonNode struct {
+	Id         int32       `json:"id"`
+	Kind       string      `json:"kind"` // root, internal or leaf
+	Suffix     *int32      `json:"suffix,omitempty"`
+	SuffixLink *int32      `json:"suffixLink,omitempty"`
+	Edge       *jsonEdge   `json:"edge,omitempty"`
+	Truncated  bool        `json:"truncated,omitempty"`
+	Children   []*jsonNode `json:"children,omitempty"`
+}
+
+type jsonEdge struct {
+	Start int32  `json:"start"`
+	End   int32  `json:"end"` // -1 for leaf edges, which run to the end of the data
+	Label string `json:"label"`
+}
+
+// WriteJSON writes the tree as a JSON document of nested nodes, children in order of their first value
+func WriteJSON(w io.Writer, tree SuffixTree, opts *ExportOptions) error {
+	e := newExporter(tree, opts)
+	var convert func(node Node, depth int) *jsonNode
+	convert = func(node Node, depth int) *jsonNode {
+		result := &jsonNode{Id: node.Id(), Kind: "internal"}
+		switch {
+		case node.isRoot():
+			result.Kind = "root"
+		case node.IsLeaf():
+			result.Kind = "leaf"
+			suffix := node.SuffixOffset()
+			result.Suffix = &suffix
+		}
+		if edge := node.IncomingEdge(); edge != nil {
+			result.Edge = &jsonEdge{edge.StartOffset, edge.EndOffset,
+				edgeLabel(tree.DataSource(), edge, e.opts.MaxLabelLength)}
+		}
+		if link := node.SuffixLink(); e.opts.SuffixLinks && link != nil && !node.isRoot() {
+			id := link.Id()
+			result.SuffixLink = &id
+		}
+		if e.truncatedAt(depth) {
+			result.Truncated = !node.IsLeaf()
+			return result
+		}
+		for _, key := range sortedChildKeys(node) {
+			result.Children = append(result.Children, convert(node.NodeFollowing(key), depth+1))
+		}
+		return result
+	}
+	document := jsonTree{convert(tree.Root(), 0)}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+	return sourceErr(tree.DataSource())
+}
@@ -0,0 +1,127 @@
+package suffixtree
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func exportJSON(t *testing.T, tree SuffixTree, opts *ExportOptions) *jsonNode {
+	t.Helper()
+	var out bytes.Buffer
+	if err := WriteJSON(&out, tree, opts); err != nil {
+		t.Fatal(err)
+	}
+	var document jsonTree
+	if err := json.Unmarshal(out.Bytes(), &document); err != nil {
+		t.Fatalf("%v in %s", err, out.String())
+	}
+	return document.Root
+}
+
+// the labels on the way down to each leaf of the exported tree spell its suffix
+func TestWriteJSONSuffixes(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		s := randomString(r, r.Intn(30), []string{"a", "ab", "abc", "aé"}[i%4])
+		tree := buildString(t, s)
+		suffixes := map[int32]string{}
+		nodes := 0
+		var walk func(node *jsonNode, path string)
+		walk = func(node *jsonNode, path string) {
+			nodes++
+			if node.Edge != nil {
+				path += node.Edge.Label
+			}
+			if node.Kind == "leaf" {
+				suffixes[*node.Suffix] = path
+			}
+			if (node.Kind == "leaf") == (len(node.Children) > 0) {
+				t.Fatalf("%q: %s node %d has %d children", s, node.Kind, node.Id, len(node.Children))
+			}
+			for _, child := range node.Children {
+				walk(child, path)
+			}
+		}
+		root := exportJSON(t, tree, nil)
+		if root.Kind != "root" || root.Edge != nil {
+			t.Fatalf("%q: exported root %+v", s, root)
+		}
+		walk(root, "")
+		values := []rune(s)
+		if len(suffixes) != len(values)+1 {
+			t.Fatalf("%q: exported %d leaves", s, len(suffixes))
+		}
+		for suffix, path := range suffixes {
+			if want := string(values[suffix:]) + "$"; path != want {
+				t.Errorf("%q: leaf %d spells %q, want %q", s, suffix, path, want)
+			}
+		}
+		if want := countNodes(tree.Root()); nodes != want {
+			t.Errorf("%q: exported %d nodes, the tree has %d", s, nodes, want)
+		}
+	}
+}
+
+func countNodes(node Node) int {
+	count := 1
+	for _, child := range node.OutgoingNodes() {
+		count += countNodes(child)
+	}
+	return count
+}
+
+func TestWriteJSONOptions(t *testing.T) {
+	tree := buildString(t, "mississippi")
+	var walk func(node *jsonNode, depth int)
+	walk = func(node *jsonNode, depth int) {
+		if depth > 2 {
+			t.Errorf("node %d is %d edges below the root", node.Id, depth)
+		}
+		if depth == 2 && node.Kind == "internal" && !node.Truncated {
+			t.Errorf("node %d at the maximum depth is not marked truncated", node.Id)
+		}
+		if node.Edge != nil {
+			label := strings.TrimSuffix(node.Edge.Label, "...")
+			if utf8.RuneCountInString(label) > 3 || label != node.Edge.Label && utf8.RuneCountInString(label) != 3 {
+				t.Errorf("node %d has label %q", node.Id, node.Edge.Label)
+			}
+		}
+		if node.Kind != "root" && node.SuffixLink == nil {
+			t.Errorf("node %d has no suffix link", node.Id)
+		}
+		for _, child := range node.Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(exportJSON(t, tree, &ExportOptions{MaxDepth: 2, MaxLabelLength: 3, SuffixLinks: true}), 0)
+}
+
+func TestWriteDOT(t *testing.T) {
+	tree := buildString(t, "banana")
+	var out bytes.Buffer
+	if err := WriteDOT(&out, tree, &ExportOptions{SuffixLinks: true, Title: `"banana"`, Highlight: []int32{tree.Root().Id()}}); err != nil {
+		t.Fatal(err)
+	}
+	dot := out.String()
+	nodes := countNodes(tree.Root())
+	if !strings.HasPrefix(dot, "digraph suffixtree {\n") || !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("not a digraph:\n%s", dot)
+	}
+	if !strings.Contains(dot, `label="\"banana\"";`) {
+		t.Errorf("title is not escaped:\n%s", dot)
+	}
+	// a tree edge to every node but the root, and a dashed link from every internal node and leaf
+	if edges := strings.Count(dot, "[label="); edges != nodes-1 {
+		t.Errorf("%d edges for %d nodes:\n%s", edges, nodes, dot)
+	}
+	if links := strings.Count(dot, "style=dashed"); links != nodes-1 {
+		t.Errorf("%d suffix links for %d nodes:\n%s", links, nodes, dot)
+	}
+	if leaves := strings.Count(dot, "shape=box"); leaves != 7 {
+		t.Errorf("%d leaves for 7 suffixes:\n%s", leaves, dot)
+	}
+}
@@ -0,0 +1,190 @@
+package suffixtree
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+)
+
+// An FMIndex (Ferragina and Manzini) answers the same queries as a Searcher over a tree, from the
+// Burrows-Wheeler transform of the data and sampled tables, in a fraction of the tree's memory.
+// It is built once from the whole data source and is read only, so it can be queried concurrently.
+//
+// Count takes time proportional to the length of the sequence, Find takes up to SuffixSampling
+// more steps for each occurrence.
+type FMIndex struct {
+	dataSource DataSource
+	length     int32 // values in the data, the index has one more row for the empty suffix
+	ranks      map[STKey]int32
+	bwt        []int32 // value ranks of the transform, 0 for the end of the data
+
+	// c[r] is the number of rows starting with a value of rank below r
+	c []int32
+	// the occurrences of each rank before every occurrenceSampling'th row, sigma+1 counts per sample
+	occurrenceSampling int32
+	occurrences        []int32
+
+	// rows whose suffix offset is a multiple of suffixSampling are marked, and their offsets kept
+	suffixSampling int32
+	marked         []uint64
+	markedBefore   []int32 // marked rows before each word of marked
+	samples        []int32
+}
+
+// FMIndexOptions trade the size of the index for query time, a nil *FMIndexOptions uses the defaults
+type FMIndexOptions struct {
+	// rows between occurrence table samples, defaults to 64
+	OccurrenceSampling int
+	// the offset of every SuffixSampling'th suffix is kept for Find, defaults to 32
+	SuffixSampling int
+}
+
+const (
+	defaultOccurrenceSampling = 64
+	defaultSuffixSampling     = 32
+)
+
+// BuildFMIndex reads the data source to the end, returning ctx.Err() if the context ends first
+func BuildFMIndex(ctx context.Context, dataSource DataSource, opts *FMIndexOptions) (*FMIndex, error) {
+	text, err := readTerminatedText(ctx, dataSource)
+	if err != nil {
+		return nil, err
+	}
+	n := text.length() - 1
+	text = text.slice(0, n)
+
+	index := &FMIndex{
+		dataSource:         dataSource,
+		length:             n,
+		ranks:              make(map[STKey]int32),
+		occurrenceSampling: defaultOccurrenceSampling,
+		suffixSampling:     defaultSuffixSampling,
+	}
+	if opts != nil && opts.OccurrenceSampling > 0 {
+		index.occurrenceSampling = int32(opts.OccurrenceSampling)
+	}
+	if opts != nil && opts.SuffixSampling > 0 {
+		index.suffixSampling = int32(opts.SuffixSampling)
+	}
+
+	// ranks start at 1, below them is the end of the data
+	distinct := make([]STKey, 0)
+	for offset := int32(0); offset < n; offset++ {
+		value := text.at(offset)
+		if _, ok := index.ranks[value]; !ok {
+			index.ranks[value] = 0
+			distinct = append(distinct, value)
+		}
+	}
+	sort.Slice(distinct, func(i, j int) bool { return distinct[i] < distinct[j] })
+	for i, value := range distinct {
+		index.ranks[value] = int32(i) + 1
+	}
+	sigma := int32(len(distinct)) + 1
+
+	// the empty suffix sorts first
+	suffixes := append([]int32{n}, suffixArray(text)...)
+	rows := n + 1
+	index.bwt = make([]int32, rows)
+	index.c = make([]int32, sigma+1)
+	index.occurrences = make([]int32, ((rows+index.occurrenceSampling-1)/index.occurrenceSampling+1)*sigma)
+	index.marked = make([]uint64, (rows+63)/64)
+	counts := make([]int32, sigma)
+	for row, offset := range suffixes {
+		if int32(row)%index.occurrenceSampling == 0 {
+			copy(index.occurrences[int32(row)/index.occurrenceSampling*sigma:], counts)
+		}
+		rank := int32(0)
+		if offset > 0 {
+			rank = index.ranks[text.at(offset-1)]
+		}
+		index.bwt[row] = rank
+		counts[rank]++
+		if offset%index.suffixSampling == 0 {
+			index.marked[row/64] |= 1 << uint(row%64)
+			index.samples = append(index.samples, offset)
+		}
+	}
+	if rows%index.occurrenceSampling == 0 {
+		copy(index.occurrences[rows/index.occurrenceSampling*sigma:], counts)
+	}
+	for rank := int32(1); rank <= sigma; rank++ {
+		index.c[rank] = index.c[rank-1] + counts[rank-1]
+	}
+	index.markedBefore = make([]int32, len(index.marked))
+	for i := 1; i < len(index.marked); i++ {
+		index.markedBefore[i] = index.markedBefore[i-1] + int32(bits.OnesCount64(index.marked[i-1]))
+	}
+	return index, sourceErr(dataSource)
+}
+
+func (index *FMIndex) DataSource() DataSource {
+	return index.dataSource
+}
+
+func (index *FMIndex) NumberValues() int32 {
+	return index.length
+}
+
+// the occurrences of rank in the transform before row
+func (index *FMIndex) occurrence(rank, row int32) int32 {
+	sigma := int32(len(index.c)) - 1
+	sample := row / index.occurrenceSampling
+	count := index.occurrences[sample*sigma+rank]
+	for i := sample * index.occurrenceSampling; i < row; i++ {
+		if index.bwt[i] == rank {
+			count++
+		}
+	}
+	return count
+}
+
+// the rows [lo, hi) of the suffixes starting with the sequence, lo == hi if there are none
+func (index *FMIndex) rows(sequence []STKey) (int32, int32) {
+	lo, hi := int32(0), index.length+1
+	for i := len(sequence) - 1; i >= 0 && lo < hi; i-- {
+		rank, ok := index.ranks[sequence[i]]
+		if !ok {
+			return 0, 0
+		}
+		lo = index.c[rank] + index.occurrence(rank, lo)
+		hi = index.c[rank] + index.occurrence(rank, hi)
+	}
+	return lo, hi
+}
+
+// the offset of the suffix in row, following the transform back to a marked row
+func (index *FMIndex) locate(row int32) int32 {
+	steps := int32(0)
+	for {
+		word, bit := row/64, uint(row%64)
+		if index.marked[word]&(1<<bit) != 0 {
+			sample := index.markedBefore[word] + int32(bits.OnesCount64(index.marked[word]&(1<<bit-1)))
+			return index.samples[sample] + steps
+		}
+		rank := index.bwt[row]
+		if rank == 0 {
+			// the suffix at offset 0
+			return steps
+		}
+		row = index.c[rank] + index.occurrence(rank, row)
+		steps++
+	}
+}
+
+// Count returns the number of occurrences of the sequence, as the tree's Counter does
+func (index *FMIndex) Count(sequence []STKey) (int, error) {
+	lo, hi := index.rows(sequence)
+	return int(hi - lo), nil
+}
+
+// Find returns the sorted offsets of every occurrence of the sequence, as the tree's Searcher does
+func (index *FMIndex) Find(sequence []STKey) ([]int32, error) {
+	lo, hi := index.rows(sequence)
+	result := make(int32arr, 0, hi-lo)
+	for row := lo; row < hi; row++ {
+		result = append(result, index.locate(row))
+	}
+	sort.Sort(result)
+	return result, nil
+}
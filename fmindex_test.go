@@ -0,0 +1,54 @@
+package suffixtree
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+)
+
+// the index finds and counts what a scan of the data does, whatever the sampling, and the tree's
+// Counter counts what its Searcher finds
+func TestFMIndexFind(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		alphabet := []string{"a", "ab", "abcd", "ab$", "aé€"}[i%5]
+		s := randomString(r, r.Intn(200), alphabet)
+		var opts *FMIndexOptions
+		if i%3 != 0 {
+			opts = &FMIndexOptions{OccurrenceSampling: 1 + r.Intn(10), SuffixSampling: 1 + r.Intn(10)}
+		}
+		index, err := BuildFMIndex(context.Background(), NewStringDataSource(s), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index.NumberValues() != int32(len([]rune(s))) {
+			t.Fatalf("%q: index of %d values", s, index.NumberValues())
+		}
+		tree := buildString(t, s)
+		searcher := NewSearcher(tree.Root(), tree.DataSource())
+		for q := 0; q < 30; q++ {
+			pattern := randomString(r, 1+r.Intn(5), alphabet+"x")
+			want := occurrencesIn(s, pattern)
+			found, err := index.Find(stringKeys(pattern))
+			if err != nil || !sameOffsets(found, want) {
+				t.Fatalf("%q %+v: Find(%q) = %v, %v, want %v", s, opts, pattern, found, err, want)
+			}
+			count, err := index.Count(stringKeys(pattern))
+			if err != nil || count != len(want) {
+				t.Fatalf("%q %+v: Count(%q) = %d, %v, want %d", s, opts, pattern, count, err, len(want))
+			}
+			treeFound, _ := searcher.Find(stringKeys(pattern))
+			if count, err := searcher.(Counter).Count(stringKeys(pattern)); err != nil || count != len(treeFound) {
+				t.Fatalf("%q: the tree counts %d of %q, %v, and finds %d", s, count, pattern, err, len(treeFound))
+			}
+		}
+	}
+}
+
+func TestFMIndexCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := BuildFMIndex(ctx, NewStringDataSource("abracadabra"), nil); err != context.Canceled {
+		t.Errorf("BuildFMIndex with a cancelled context returned %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+package suffixtree
+
+import (
+	"errors"
+	"sort"
+)
+
+// A GeneralizedDataSource holds several sequences one after another, each followed by its own
+// separator, so that a single tree holds the suffixes of all of them.  Separators are negative
+// and distinct, so no path below an internal node runs from one sequence into the next.
+type GeneralizedDataSource struct {
+	*stringDataSource
+	starts []int32
+}
+
+// Separator is the value following sequence i in a GeneralizedDataSource
+func Separator(i int) STKey {
+	return STKey(-1 - i)
+}
+
+func NewGeneralizedDataSource(sequences ...[]STKey) *GeneralizedDataSource {
+	runes := []rune{}
+	starts := make([]int32, 0, len(sequences))
+	for i, sequence := range sequences {
+		starts = append(starts, int32(len(runes)))
+		for _, value := range sequence {
+			runes = append(runes, rune(value))
+		}
+		runes = append(runes, rune(Separator(i)))
+	}
+	return &GeneralizedDataSource{&stringDataSource{runes: runes}, starts}
+}
+
+func (g *GeneralizedDataSource) NumberSequences() int {
+	return len(g.starts)
+}
+
+// SequenceStart is the offset of the first value of sequence i
+func (g *GeneralizedDataSource) SequenceStart(i int) int32 {
+	return g.starts[i]
+}
+
+// SequenceLength is the number of values in sequence i, not counting its separator
+func (g *GeneralizedDataSource) SequenceLength(i int) int32 {
+	end := int32(len(g.runes))
+	if i+1 < len(g.starts) {
+		end = g.starts[i+1]
+	}
+	return end - g.starts[i] - 1
+}
+
+// SequenceAt returns the sequence holding an offset, and the offset within that sequence.
+// A separator belongs to the sequence it follows, the Terminator to none (-1).
+func (g *GeneralizedDataSource) SequenceAt(offset int32) (int, int32) {
+	if offset < 0 || int(offset) >= len(g.runes) {
+		return -1, offset
+	}
+	i := sort.Search(len(g.starts), func(i int) bool { return g.starts[i] > offset }) - 1
+	return i, offset - g.starts[i]
+}
+
+// a set of sequence numbers
+type sequenceSet []uint64
+
+func newSequenceSet(n int) sequenceSet {
+	return make(sequenceSet, (n+63)/64)
+}
+
+func (set sequenceSet) add(i int) {
+	set[i/64] |= 1 << uint(i%64)
+}
+
+func (set sequenceSet) union(other sequenceSet) {
+	for i := range set {
+		set[i] |= other[i]
+	}
+}
+
+func (set sequenceSet) count() int {
+	count := 0
+	for _, word := range set {
+		for ; word != 0; word &= word - 1 {
+			count++
+		}
+	}
+	return count
+}
+
+// A CommonSubstring is a run of values shared by several sequences
+type CommonSubstring struct {
+	Length int32
+	// for each sequence, the offset within the sequence of its first occurrence, -1 if it has none
+	Offsets []int32
+}
+
+// LongestCommonSubstring finds the longest run of values occurring in at least minSequences of the
+// sequences of a tree built over a GeneralizedDataSource, or in all of them if minSequences <= 0.
+// The Length is 0 when there is no such run.
+func LongestCommonSubstring(tree SuffixTree, minSequences int) (CommonSubstring, error) {
+	g, ok := tree.DataSource().(*GeneralizedDataSource)
+	if !ok {
+		return CommonSubstring{}, errors.New("suffixtree: common substrings need a tree built over a GeneralizedDataSource")
+	}
+	numberSequences := g.NumberSequences()
+	if minSequences <= 0 || minSequences > numberSequences {
+		minSequences = numberSequences
+	}
+
+	var best Node
+	bestDepth := int32(0)
+	var sequencesBelow func(node Node, depth int32) sequenceSet
+	sequencesBelow = func(node Node, depth int32) sequenceSet {
+		set := newSequenceSet(numberSequences)
+		if node.IsLeaf() {
+			if i, offset := g.SequenceAt(node.SuffixOffset()); i >= 0 {
+				set.add(i)
+				// a suffix occurs in only one sequence, so it is a candidate when one is enough
+				if length := g.SequenceLength(i) - offset; minSequences == 1 && length > bestDepth {
+					best, bestDepth = node, length
+				}
+			}
+			return set
+		}
+		for _, child := range node.OutgoingNodes() {
+			childDepth := depth
+			if !child.IsLeaf() {
+				childDepth += child.IncomingEdge().length()
+			}
+			set.union(sequencesBelow(child, childDepth))
+		}
+		if depth > bestDepth && set.count() >= minSequences {
+			best, bestDepth = node, depth
+		}
+		return set
+	}
+	sequencesBelow(tree.Root(), 0)
+
+	result := CommonSubstring{Length: bestDepth, Offsets: make([]int32, numberSequences)}
+	for i := range result.Offsets {
+		result.Offsets[i] = -1
+	}
+	if best != nil {
+		for _, suffix := range best.ChildSuffixes(nil) {
+			if i, offset := g.SequenceAt(suffix); i >= 0 && (result.Offsets[i] < 0 || offset < result.Offsets[i]) {
+				result.Offsets[i] = offset
+			}
+		}
+	}
+	return result, g.Err()
+}
@@ -0,0 +1,189 @@
+package suffixtree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrBadIndex is returned by ReadIndex for input that WriteIndex did not produce
+var ErrBadIndex = errors.New("suffixtree: not a valid index")
+
+// An index holds a finished tree and the values it was built from, so it can be searched
+// again without rebuilding.  The layout, all numbers as varints:
+//
+//	magic "STX1"
+//	number of values, then each value
+//	the nodes in preorder, children in order of their first value:
+//	  id, kind, and for nodes below the root the edge start and end,
+//	  then the suffix offset of a leaf or the number of children of the root or an internal node
+//	the preorder position of each internal node's suffix link, in preorder
+//
+// Leaf suffix links are not stored, they follow from the suffix offsets.
+const indexMagic = "STX1"
+
+const (
+	indexRoot byte = iota
+	indexInternal
+	indexLeaf
+)
+
+// WriteIndex saves a finished tree.  The data source must know its length,
+// as the string and file data sources do.
+func WriteIndex(w io.Writer, tree SuffixTree) error {
+	dataSource := tree.DataSource()
+	sized, ok := dataSource.(lengthKnown)
+	if !ok {
+		return errors.New("suffixtree: an index needs a data source with a known length")
+	}
+	values, err := keysOf(dataSource, int32(sized.Len()))
+	if err != nil {
+		return err
+	}
+
+	out := newVarintWriter(w, indexMagic)
+	out.uvarint(uint64(len(values)))
+	for _, value := range values {
+		out.varint(int64(value))
+	}
+
+	position := make(map[Node]int)
+	internals := []Node{}
+	var writeNode func(node Node)
+	writeNode = func(node Node) {
+		position[node] = len(position)
+		out.uvarint(uint64(node.Id()))
+		switch {
+		case node.isRoot():
+			out.WriteByte(indexRoot)
+		case node.IsLeaf():
+			out.WriteByte(indexLeaf)
+		default:
+			out.WriteByte(indexInternal)
+			internals = append(internals, node)
+		}
+		if edge := node.IncomingEdge(); edge != nil {
+			out.uvarint(uint64(edge.StartOffset))
+			out.varint(int64(edge.EndOffset))
+		}
+		if node.IsLeaf() {
+			out.uvarint(uint64(node.SuffixOffset()))
+			return
+		}
+		keys := sortedChildKeys(node)
+		out.uvarint(uint64(len(keys)))
+		for _, key := range keys {
+			writeNode(node.NodeFollowing(key))
+		}
+	}
+	writeNode(tree.Root())
+	for _, node := range internals {
+		link, ok := position[node.SuffixLink()]
+		if !ok {
+			return fmt.Errorf("%w: node %d has no suffix link", ErrMalformedTree, node.Id())
+		}
+		out.uvarint(uint64(link))
+	}
+	return out.Flush()
+}
+
+// ReadIndex loads a tree saved by WriteIndex, its data source holds the saved values in memory
+func ReadIndex(r io.Reader) (SuffixTree, error) {
+	in, err := newVarintReader(r, indexMagic, ErrBadIndex)
+	if err != nil {
+		return nil, err
+	}
+	// the first malformed read, later reads are skipped
+	var readErr error
+	readUvarint := func(limit uint64) uint64 {
+		x, err := in.uvarint()
+		if err == nil && x > limit {
+			err = ErrBadIndex
+		}
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		return x
+	}
+	readVarint := func() int64 {
+		x, err := in.varint()
+		if err != nil && readErr == nil {
+			readErr = err
+		}
+		return x
+	}
+	n := int32(readUvarint(1<<31 - 2))
+	if readErr != nil {
+		return nil, readErr
+	}
+	values := []STKey{}
+	for i := int32(0); i < n && readErr == nil; i++ {
+		values = append(values, STKey(readVarint()))
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	dataSource := NewKeyDataSource(values)
+
+	nodes := []Node{}
+	internals := []Node{}
+	var readNode func(parent Node) Node
+	readNode = func(parent Node) Node {
+		id := int32(readUvarint(1<<31 - 1))
+		kind, err := in.ReadByte()
+		if err != nil || readErr != nil || (kind == indexRoot) != (parent == nil) || kind > indexLeaf {
+			if readErr == nil {
+				readErr = ErrBadIndex
+			}
+			return nil
+		}
+		var node Node
+		if parent == nil {
+			node = NewRootNode(id)
+		} else {
+			start := int32(readUvarint(uint64(n)))
+			end := int32(readVarint())
+			if readErr == nil && (end < FinalOffset || end > n || (end != FinalOffset && end < start) || (end == FinalOffset) != (kind == indexLeaf)) {
+				readErr = ErrBadIndex
+			}
+			if readErr != nil {
+				return nil
+			}
+			var edge *Edge
+			if kind == indexLeaf {
+				edge, node = newLeafForSuffix(id, parent, start, int32(readUvarint(uint64(n))))
+			} else {
+				edge = NewEdge(start, end)
+				node = NewInternalNode(id, parent, edge)
+				internals = append(internals, node)
+			}
+			key := dataSource.KeyAtOffset(start)
+			if parent.NodeFollowing(key) != nil {
+				readErr = ErrBadIndex
+				return nil
+			}
+			parent.AddOutgoingEdgeNode(key, edge, node)
+		}
+		nodes = append(nodes, node)
+		if kind != indexLeaf {
+			children := readUvarint(uint64(n) + 1)
+			for i := uint64(0); i < children && readErr == nil; i++ {
+				readNode(node)
+			}
+		}
+		return node
+	}
+	root := readNode(nil)
+	for _, node := range internals {
+		link := readUvarint(uint64(len(nodes)) - 1)
+		if readErr != nil {
+			break
+		}
+		node.SetSuffixLink(nodes[link])
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	linkLeaves(root, dataSource, n)
+	return NewSuffixTree(root, dataSource), nil
+}
@@ -0,0 +1,73 @@
+package suffixtree
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func reloaded(t *testing.T, tree SuffixTree) SuffixTree {
+	t.Helper()
+	var index bytes.Buffer
+	if err := WriteIndex(&index, tree); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadIndex(&index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return read
+}
+
+// a tree read back from its index, including one over several sequences, finds what the tree did
+func TestIndexRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		alphabet := []string{"ab", "abc", "ab$"}[i%3]
+		sequences := [][]STKey{}
+		for j := 0; j < 1+i%4; j++ {
+			sequences = append(sequences, stringKeys(randomString(r, r.Intn(20), alphabet)))
+		}
+		u := NewUkkonen(NewGeneralizedDataSource(sequences...))
+		for u.Extend() {
+		}
+		if err := u.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		tree := u.Tree()
+		read := reloaded(t, tree)
+		if violations := Validate(read); len(violations) > 0 {
+			t.Fatalf("%v: %v", sequences, violations)
+		}
+		searcher, readSearcher := NewSearcher(tree.Root(), tree.DataSource()), NewSearcher(read.Root(), read.DataSource())
+		for q := 0; q < 50; q++ {
+			pattern := stringKeys(randomString(r, 1+r.Intn(4), alphabet))
+			if q%10 == 0 {
+				pattern = append(pattern, Separator(r.Intn(len(sequences))))
+			}
+			want, err := searcher.Find(pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found, err := readSearcher.Find(pattern)
+			if err != nil || !reflect.DeepEqual(found, want) {
+				t.Fatalf("%v: Find(%v) = %v, %v after reloading, %v before", sequences, pattern, found, err, want)
+			}
+		}
+	}
+}
+
+func TestReadIndexRejectsDamage(t *testing.T) {
+	var index bytes.Buffer
+	if err := WriteIndex(&index, buildString(t, "mississippi")); err != nil {
+		t.Fatal(err)
+	}
+	written := index.Bytes()
+	for _, damaged := range [][]byte{nil, []byte("STX0"), written[:len(written)/2], written[:len(written)-1]} {
+		if _, err := ReadIndex(bytes.NewReader(damaged)); !errors.Is(err, ErrBadIndex) {
+			t.Errorf("ReadIndex of %d bytes returned %v", len(damaged), err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package suffixtree
+
+import "errors"
+
+// KmerOptions filter the k-mers KmerCounts reports, a nil *KmerOptions reports every k-mer
+// ending at a node
+type KmerOptions struct {
+	// report only k-mers occurring at least MinCount times, and at most MaxCount times if it is above 0
+	MinCount int32
+	MaxCount int32
+	// also report the k-mers ending inside an edge, rather than only those ending at a node.
+	// Those ending at a node are followed by more than one value, the others by only one.
+	IncludeMidEdge bool
+}
+
+// KmerCounts calls emit with each run of k values in the data of a finished tree and the number
+// of times it occurs, in lexicographic order.  Runs past the end of the data, or including a
+// separator of a GeneralizedDataSource, are not k-mers.  If emit returns an error the walk stops
+// and returns it.
+//
+// A k-mer's count is the number of occurrences below where its path ends, so only the top k
+// values of the tree are visited, not the suffixes below them.
+func KmerCounts(tree SuffixTree, k int32, opts *KmerOptions, emit func(kmer []STKey, count int32) error) error {
+	dataSource := tree.DataSource()
+	sized, ok := dataSource.(lengthKnown)
+	if !ok {
+		return errors.New("suffixtree: k-mers need a data source with a known length")
+	}
+	n := int32(sized.Len())
+	if opts == nil {
+		opts = &KmerOptions{}
+	}
+	if k < 1 {
+		return errors.New("suffixtree: k-mers must have at least one value")
+	}
+	generalized, _ := dataSource.(*GeneralizedDataSource)
+	occurrences := countOccurrences(tree)
+
+	path := make([]STKey, 0, k)
+	var visit func(node Node, depth int32) error
+	visit = func(node Node, depth int32) error {
+		for _, key := range sortedChildKeys(node) {
+			child := node.outgoingNodeMap()[key]
+			edge := child.IncomingEdge()
+			if child.IsLeaf() && edge.StartOffset+k-depth > n {
+				// the data ends before k values
+				continue
+			}
+			if !child.IsLeaf() && depth+edge.length() < k {
+				path = append(path, keysAt(dataSource, edge.StartOffset, edge.length())...)
+				if err := visit(child, depth+edge.length()); err != nil {
+					return err
+				}
+				path = path[:depth]
+				continue
+			}
+			atNode := !child.IsLeaf() && depth+edge.length() == k
+			c := occurrences.at(child, k)
+			if (!atNode && !opts.IncludeMidEdge) || c < opts.MinCount || (opts.MaxCount > 0 && c > opts.MaxCount) {
+				continue
+			}
+			kmer := append(append(make([]STKey, 0, k), path...), keysAt(dataSource, edge.StartOffset, k-depth)...)
+			if generalized != nil && hasSeparator(kmer) {
+				continue
+			}
+			if err := emit(kmer, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := visit(tree.Root(), 0); err != nil {
+		return err
+	}
+	return sourceErr(dataSource)
+}
+
+func keysAt(dataSource DataSource, offset, length int32) []STKey {
+	keys := make([]STKey, length)
+	for i := range keys {
+		keys[i] = dataSource.KeyAtOffset(offset + int32(i))
+	}
+	return keys
+}
+
+func hasSeparator(values []STKey) bool {
+	for _, value := range values {
+		if value < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// KmerSpectrum returns the number of k-mers occurring each number of times, for the k-mers
+// KmerCounts reports with the same options
+func KmerSpectrum(tree SuffixTree, k int32, opts *KmerOptions) (map[int32]int64, error) {
+	spectrum := make(map[int32]int64)
+	err := KmerCounts(tree, k, opts, func(kmer []STKey, count int32) error {
+		spectrum[count]++
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spectrum, nil
+}
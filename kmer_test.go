@@ -0,0 +1,61 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// every run of k values in s and the number of times it occurs
+func bruteKmers(s string, k int) map[string]int32 {
+	counts := make(map[string]int32)
+	text := []rune(s)
+	for i := 0; i+k <= len(text); i++ {
+		counts[string(text[i:i+k])]++
+	}
+	return counts
+}
+
+func kmerCounts(t *testing.T, tree SuffixTree, k int32, opts *KmerOptions) map[string]int32 {
+	t.Helper()
+	counts := make(map[string]int32)
+	err := KmerCounts(tree, k, opts, func(kmer []STKey, count int32) error {
+		values := []rune{}
+		for _, value := range kmer {
+			values = append(values, rune(value))
+		}
+		counts[string(values)] = count
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return counts
+}
+
+func TestKmerCountsMatchBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		alphabet := []string{"acgt", "ab$", "a$", "$"}[i%4]
+		s := randomString(r, r.Intn(40), alphabet)
+		tree := buildWith(t, s, Algorithm(i%4))
+		for k := 1; k <= 4; k++ {
+			want := bruteKmers(s, k)
+			if got := kmerCounts(t, tree, int32(k), &KmerOptions{IncludeMidEdge: true}); !reflect.DeepEqual(got, want) {
+				t.Fatalf("%d-mers of %q = %v, want %v", k, s, got, want)
+			}
+			for kmer, count := range kmerCounts(t, tree, int32(k), nil) {
+				if want[kmer] != count {
+					t.Fatalf("%d-mer %q of %q counted %d times, want %d", k, kmer, s, count, want[kmer])
+				}
+			}
+		}
+	}
+}
+
+func TestKmerCountsDollarInData(t *testing.T) {
+	got := kmerCounts(t, buildString(t, "abb$abbbb"), 1, &KmerOptions{IncludeMidEdge: true})
+	if want := map[string]int32{"a": 2, "b": 6, "$": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("1-mers = %v, want %v", got, want)
+	}
+}
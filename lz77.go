@@ -0,0 +1,164 @@
+package suffixtree
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// A Factor is one phrase of the LZ77 factorization: the longest run of values starting at
+// Start that also starts at an earlier offset, or a single new value when there is none.
+// The earlier occurrence may overlap the phrase.
+type Factor struct {
+	Start  int32
+	Length int32 // 0 for a value that does not occur earlier
+	Source int32 // the earliest offset the run also starts at, -1 for a new value
+	Value  STKey // the new value, when Length is 0
+}
+
+// LZ77 returns the LZ77 factorization of the data in a finished tree, in order.
+//
+// Each phrase follows the path of its own suffix down the tree value by value, as long as the
+// subtree below holds a suffix starting earlier.  The smallest offset in every subtree is found
+// first, so the factorization takes time linear in the length of the data.
+func LZ77(tree SuffixTree) ([]Factor, error) {
+	dataSource := tree.DataSource()
+	sized, ok := dataSource.(lengthKnown)
+	if !ok {
+		return nil, errors.New("suffixtree: a factorization needs a data source with a known length")
+	}
+	n := int32(sized.Len())
+	text, err := keysOf(dataSource, n)
+	if err != nil {
+		return nil, err
+	}
+
+	firstOffset := firstOffsets(tree.Root(), n)
+	traverser := NewTraverser(dataSource)
+
+	factors := []Factor{}
+	for start := int32(0); start < n; {
+		location := NewLocation(tree.Root())
+		depth, source := int32(0), int32(-1)
+		for start+depth < n {
+			found, err := traverser.traverseDownValue(location, text[start+depth])
+			if err != nil {
+				return nil, err
+			}
+			if !found {
+				break
+			}
+			below := location.Base.SuffixOffset()
+			if !location.Base.IsLeaf() {
+				below = firstOffset[location.Base]
+			}
+			// only a suffix starting before the phrase can be its source
+			if below >= start {
+				break
+			}
+			depth, source = depth+1, below
+		}
+		if depth == 0 {
+			factors = append(factors, Factor{Start: start, Source: -1, Value: text[start]})
+			start++
+			continue
+		}
+		factors = append(factors, Factor{Start: start, Length: depth, Source: source})
+		start += depth
+	}
+	return factors, sourceErr(dataSource)
+}
+
+// LZ77Complexity is the number of phrases in the LZ77 factorization, a measure of how much
+// new material the data holds
+func LZ77Complexity(tree SuffixTree) (int, error) {
+	factors, err := LZ77(tree)
+	return len(factors), err
+}
+
+// ErrBadLZ77 is returned by DecompressLZ77 for input CompressLZ77 did not write
+var ErrBadLZ77 = errors.New("suffixtree: not valid LZ77 compressed data")
+
+const lz77Magic = "STZ1"
+
+// CompressLZ77 compresses the bytes read from r, writing the factorization of a tree built
+// over them to w.
+//
+// The format is "STZ1", then the number of bytes and the phrases as varints: the length of
+// each phrase, followed by the new byte for a length of 0, or the distance back to the source.
+func CompressLZ77(w io.Writer, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	values := make([]STKey, len(data))
+	for i, b := range data {
+		values[i] = STKey(b)
+	}
+	tree, err := Build(context.Background(), NewKeyDataSource(values), &BuildOptions{Algorithm: SuffixArrayAlgorithm})
+	if err != nil {
+		return err
+	}
+	factors, err := LZ77(tree)
+	if err != nil {
+		return err
+	}
+
+	out := newVarintWriter(w, lz77Magic)
+	out.uvarint(uint64(len(data)))
+	for _, factor := range factors {
+		out.uvarint(uint64(factor.Length))
+		if factor.Length == 0 {
+			out.WriteByte(byte(factor.Value))
+		} else {
+			out.uvarint(uint64(factor.Start - factor.Source))
+		}
+	}
+	return out.Flush()
+}
+
+// DecompressLZ77 writes the bytes CompressLZ77 compressed to w
+func DecompressLZ77(w io.Writer, r io.Reader) error {
+	in, err := newVarintReader(r, lz77Magic, ErrBadLZ77)
+	if err != nil {
+		return err
+	}
+	length, err := in.uvarint()
+	if err != nil {
+		return err
+	}
+	if length > uint64(^uint32(0)>>1) {
+		return ErrBadLZ77
+	}
+
+	// every earlier byte may be copied, so all of them are kept
+	data := make([]byte, 0, length)
+	for uint64(len(data)) < length {
+		phrase, err := in.uvarint()
+		if err != nil {
+			return err
+		}
+		if phrase == 0 {
+			b, err := in.ReadByte()
+			if err != nil {
+				return in.bad(err)
+			}
+			data = append(data, b)
+			continue
+		}
+		distance, err := in.uvarint()
+		if err != nil {
+			return err
+		}
+		if distance == 0 || distance > uint64(len(data)) || phrase > length-uint64(len(data)) {
+			return ErrBadLZ77
+		}
+		// byte by byte, the source may overlap the phrase
+		source := len(data) - int(distance)
+		for i := 0; i < int(phrase); i++ {
+			data = append(data, data[source+i])
+		}
+	}
+	_, err = w.Write(data)
+	return err
+}
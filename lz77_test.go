@@ -0,0 +1,59 @@
+package suffixtree
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// each phrase is the longest run that also starts earlier, found by comparing every earlier offset
+func TestLZ77LongestPreviousFactor(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		alphabet := []string{"ab", "ab$", "a$", "acgt"}[i%4]
+		s := randomString(r, r.Intn(40), alphabet)
+		text := stringKeys(s)
+		factors, err := LZ77(buildString(t, s))
+		if err != nil {
+			t.Fatalf("LZ77(%q): %v", s, err)
+		}
+		start := int32(0)
+		for _, factor := range factors {
+			length, source := int32(0), int32(-1)
+			for earlier := int32(0); earlier < start; earlier++ {
+				l := int32(0)
+				for start+l < int32(len(text)) && text[earlier+l] == text[start+l] {
+					l++
+				}
+				if l > length {
+					length, source = l, earlier
+				}
+			}
+			if factor.Start != start || factor.Length != length || factor.Source != source {
+				t.Fatalf("LZ77(%q) has phrase %+v at %d, the longest previous factor is %d long at %d",
+					s, factor, start, length, source)
+			}
+			if length == 0 {
+				length = 1
+			}
+			start += length
+		}
+		if start != int32(len(text)) {
+			t.Fatalf("LZ77(%q) covers %d of %d values", s, start, len(text))
+		}
+	}
+}
+
+func TestLZ77RoundTrip(t *testing.T) {
+	data := []byte("abb$abbbb$ab\x00\xff\xfe$$abb$")
+	var compressed, decompressed bytes.Buffer
+	if err := CompressLZ77(&compressed, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := DecompressLZ77(&decompressed, &compressed); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed.Bytes(), data) {
+		t.Errorf("decompressed %q, compressed %q", decompressed.Bytes(), data)
+	}
+}
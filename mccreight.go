@@ -0,0 +1,86 @@
+package suffixtree
+
+import "context"
+
+// McCreight's algorithm inserts the suffixes longest first.  The head of each suffix (its longest
+// prefix already in the tree) is found by following the suffix link from the previous head and
+// rescanning the edge below it, then scanning value by value from there.
+func buildMcCreight(ctx context.Context, factory *idFactory, text builderText) (Node, error) {
+	n := text.length()
+	root := NewRootNode(factory.NextId())
+	head, headDepth := root, int32(0)
+	for suffix := int32(0); suffix < n; suffix++ {
+		if suffix%4096 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		var from Node
+		var fromDepth int32
+		if head.isRoot() {
+			from, fromDepth = root, 0
+		} else {
+			// rescan the edge above the previous head, starting from its parent's suffix link
+			parent := head.parent()
+			start, length := head.IncomingEdge().StartOffset, head.IncomingEdge().length()
+			if parent.isRoot() {
+				from, fromDepth, start, length = root, 0, start+1, length-1
+			} else {
+				from, fromDepth = parent.SuffixLink(), headDepth-length-1
+			}
+			for length > 0 {
+				edge, child := from.outgoingEdgeNode(text.at(start))
+				edgeLength := labelLength(text, edge)
+				if edgeLength > length {
+					if fromDepth+length == n-suffix {
+						// the rest of the text occurs earlier, every remaining suffix is implicit
+						return root, nil
+					}
+					from = splitEdge(factory, text, from, child, length)
+					fromDepth += length
+					length = 0
+					break
+				}
+				from, fromDepth = child, fromDepth+edgeLength
+				start, length = start+edgeLength, length-edgeLength
+			}
+			head.SetSuffixLink(from)
+		}
+
+		var ok bool
+		if head, headDepth, ok = scanFrom(factory, text, from, fromDepth, suffix); !ok {
+			return root, nil
+		}
+		edge, leaf := newLeafForSuffix(factory.NextId(), head, suffix+headDepth, suffix)
+		head.AddOutgoingEdgeNode(text.at(suffix+headDepth), edge, leaf)
+	}
+	return root, nil
+}
+
+// scan down from node (at depth) comparing the suffix value by value, returning the node where
+// the suffix leaves the tree, splitting an edge if needed.  Returns false if the whole suffix
+// is already in the tree.
+func scanFrom(factory *idFactory, text builderText, node Node, depth, suffix int32) (Node, int32, bool) {
+	n := text.length()
+	for {
+		position := suffix + depth
+		if position == n {
+			return nil, 0, false
+		}
+		edge, child := node.outgoingEdgeNode(text.at(position))
+		if child == nil {
+			return node, depth, true
+		}
+		edgeLength := labelLength(text, edge)
+		matched := int32(1)
+		for matched < edgeLength && position+matched < n && text.at(edge.StartOffset+matched) == text.at(position+matched) {
+			matched++
+		}
+		if matched == edgeLength && !child.IsLeaf() {
+			node, depth = child, depth+edgeLength
+			continue
+		}
+		if position+matched == n || matched == edgeLength {
+			return nil, 0, false
+		}
+		return splitEdge(factory, text, node, child, matched), depth + matched, true
+	}
+}
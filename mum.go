@@ -0,0 +1,104 @@
+package suffixtree
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// A MUM is a maximal unique match between two sequences: a run of values occurring exactly once
+// in each, that cannot be extended in either direction
+type MUM struct {
+	A      int32 // offset in the first sequence
+	B      int32 // offset in the second sequence
+	Length int32
+}
+
+// MUMs returns the maximal unique matches of at least minLength values between the two sequences
+// of a tree built over a GeneralizedDataSource, in order of their offset in the first sequence.
+//
+// A match occurring once in each sequence is an internal node with just two leaves below it, one
+// from each sequence.  It ends where the sequences differ, so it is a MUM when the values before
+// its two occurrences differ too.
+func MUMs(tree SuffixTree, minLength int32) ([]MUM, error) {
+	g, ok := tree.DataSource().(*GeneralizedDataSource)
+	if !ok || g.NumberSequences() != 2 {
+		return nil, errors.New("suffixtree: MUMs need a tree built over a GeneralizedDataSource of two sequences")
+	}
+	mums := []MUM{}
+	var visit func(node Node, depth int32)
+	visit = func(node Node, depth int32) {
+		children := node.OutgoingNodes()
+		if !node.isRoot() && depth >= minLength && len(children) == 2 && children[0].IsLeaf() && children[1].IsLeaf() {
+			first, second := children[0].SuffixOffset(), children[1].SuffixOffset()
+			if first > second {
+				first, second = second, first
+			}
+			i, a := g.SequenceAt(first)
+			j, b := g.SequenceAt(second)
+			if i == 0 && j == 1 && (a == 0 || b == 0 || g.KeyAtOffset(first-1) != g.KeyAtOffset(second-1)) {
+				mums = append(mums, MUM{a, b, depth})
+			}
+		}
+		for _, child := range children {
+			if !child.IsLeaf() {
+				visit(child, depth+child.IncomingEdge().length())
+			}
+		}
+	}
+	visit(tree.Root(), 0)
+	sort.Slice(mums, func(i, j int) bool { return mums[i].A < mums[j].A })
+	return mums, g.Err()
+}
+
+// FindMUMs reads two data sources and returns their maximal unique matches of at least minLength values
+func FindMUMs(ctx context.Context, a, b DataSource, minLength int32) ([]MUM, error) {
+	sequences := make([][]STKey, 2)
+	for i, dataSource := range []DataSource{a, b} {
+		text, err := readTerminatedText(ctx, dataSource)
+		if err != nil {
+			return nil, err
+		}
+		sequences[i] = make([]STKey, text.length()-1)
+		for offset := range sequences[i] {
+			sequences[i][offset] = text.at(int32(offset))
+		}
+	}
+	tree, err := Build(ctx, NewGeneralizedDataSource(sequences...), &BuildOptions{Algorithm: SuffixArrayAlgorithm})
+	if err != nil {
+		return nil, err
+	}
+	return MUMs(tree, minLength)
+}
+
+// ChainMUMs returns the longest chain of MUMs in the same order in both sequences, the anchors of
+// an alignment of the two.  MUMs in the chain may overlap.
+func ChainMUMs(mums []MUM) []MUM {
+	sorted := make([]MUM, len(mums))
+	copy(sorted, mums)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].A < sorted[j].A })
+
+	// the longest increasing subsequence of B offsets: tails[k] is the MUM ending the chain of
+	// length k+1 with the smallest B offset, previous links each MUM to the one before it
+	tails := []int{}
+	previous := make([]int, len(sorted))
+	for i, mum := range sorted {
+		k := sort.Search(len(tails), func(k int) bool { return sorted[tails[k]].B >= mum.B })
+		previous[i] = -1
+		if k > 0 {
+			previous[i] = tails[k-1]
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	chain := make([]MUM, len(tails))
+	if len(tails) > 0 {
+		for i, k := tails[len(tails)-1], len(tails)-1; k >= 0; i, k = previous[i], k-1 {
+			chain[k] = sorted[i]
+		}
+	}
+	return chain
+}
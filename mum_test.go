@@ -0,0 +1,86 @@
+package suffixtree
+
+import (
+	"context"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// the maximal unique matches of a and b found by comparing every pair of offsets
+func bruteMUMs(a, b string, minLength int) []MUM {
+	mums := []MUM{}
+	for i := 0; i < len(a); i++ {
+		for j := 0; j < len(b); j++ {
+			if i > 0 && j > 0 && a[i-1] == b[j-1] {
+				continue
+			}
+			length := 0
+			for i+length < len(a) && j+length < len(b) && a[i+length] == b[j+length] {
+				length++
+			}
+			if length == 0 || length < minLength {
+				continue
+			}
+			match := a[i : i+length]
+			if len(occurrencesIn(a, match)) == 1 && len(occurrencesIn(b, match)) == 1 {
+				mums = append(mums, MUM{int32(i), int32(j), int32(length)})
+			}
+		}
+	}
+	sort.Slice(mums, func(i, j int) bool { return mums[i].A < mums[j].A })
+	return mums
+}
+
+// the length of the longest chain of MUMs in the same order in both sequences, they may overlap
+func longestChain(mums []MUM) int {
+	chain, longest := make([]int, len(mums)), 0
+	for i := range mums {
+		chain[i] = 1
+		for j := 0; j < i; j++ {
+			if mums[j].A < mums[i].A && mums[j].B < mums[i].B && chain[j]+1 > chain[i] {
+				chain[i] = chain[j] + 1
+			}
+		}
+		if chain[i] > longest {
+			longest = chain[i]
+		}
+	}
+	return longest
+}
+
+func TestMUMs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		a, b := randomString(r, r.Intn(60), "acgt"), randomString(r, r.Intn(60), "acgt")
+		if i%5 == 0 {
+			b = a[:len(a)/2] + strings.ToUpper(b[:len(b)/3]) + a[len(a)/2:]
+		}
+		minLength := r.Intn(4)
+		want := bruteMUMs(a, b, minLength)
+		found, err := FindMUMs(context.Background(), NewStringDataSource(a), NewStringDataSource(b), int32(minLength))
+		if err != nil || !reflect.DeepEqual(found, want) {
+			t.Fatalf("%q %q: FindMUMs(%d) = %v, %v, want %v", a, b, minLength, found, err, want)
+		}
+		u := NewUkkonen(NewGeneralizedDataSource(stringKeys(a), stringKeys(b)))
+		for u.Extend() {
+		}
+		if err := u.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		if found, err := MUMs(u.Tree(), int32(minLength)); err != nil || !reflect.DeepEqual(found, want) {
+			t.Fatalf("%q %q: MUMs(%d) = %v, %v, want %v", a, b, minLength, found, err, want)
+		}
+		chain := ChainMUMs(found)
+		for j := 1; j < len(chain); j++ {
+			if chain[j].A <= chain[j-1].A || chain[j].B <= chain[j-1].B {
+				t.Fatalf("%q %q: chain %v is out of order", a, b, chain)
+			}
+		}
+		if len(chain) != longestChain(found) {
+			t.Fatalf("%q %q: chain of %d MUMs from %v, the longest has %d", a, b, len(chain), found, longestChain(found))
+		}
+	}
+}
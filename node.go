@@ -1,6 +1,13 @@
 package suffixtree
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrMalformedTree is returned by queries that find nodes and edges that are inconsistent with each other.
+var ErrMalformedTree = errors.New("suffixtree: malformed tree")
 
 const UnspecifiedOffset int32 = -1
 const MoreThanOne = -1
@@ -13,7 +20,7 @@ type Node interface {
 	isRoot() bool
 	isInternal() bool
 	IsLeaf() bool
-	SuffixOffset() int32                         // leaf only
+	SuffixOffset() int32                         // leaf only, UnspecifiedOffset otherwise
 	ChildSuffixes(suffixOffsets []int32) []int32 // all child suffixes
 	depth() int32
 	Id() int32
@@ -29,6 +36,7 @@ type Node interface {
 
 	// child Nodes and outgoing Edges
 	AddOutgoingEdgeNode(key STKey, edge *Edge, node Node)
+	removeEdgeFollowing(key STKey)
 	outgoingEdgeNode(key STKey) (*Edge, Node)
 	addLeafEdgeNode(id int32, key STKey, offset int32) (*Edge, Node)
 	EdgeFollowing(key STKey) *Edge
@@ -70,9 +78,9 @@ type idFactory struct {
 	_id int32
 }
 
+// NextId is safe to call from several goroutines building parts of one tree
 func (factory *idFactory) NextId() int32 {
-	factory._id++
-	return factory._id
+	return atomic.AddInt32(&factory._id, 1)
 }
 
 func NewNodeIdFactory() (factory *idFactory) {
@@ -100,6 +108,7 @@ func (outgoing *hasOutgoing) AddOutgoingEdgeNode(key STKey, edge *Edge, node Nod
 
 func (outgoing *hasOutgoing) removeEdgeFollowing(key STKey) {
 	delete(outgoing.edges, key)
+	delete(outgoing.nodes, key)
 }
 
 func (outgoing *hasOutgoing) OutgoingNodes() []Node {
@@ -131,13 +140,14 @@ func (outgoing *hasOutgoing) IsLeaf() bool {
 }
 
 func (outgoing *hasOutgoing) SuffixOffset() int32 {
-	panic("no suffix for internal nodes")
+	return UnspecifiedOffset
 }
 
+// Leaves answer queries about children with nil, only adding children panics
 type noOutgoing struct{}
 
 func (node *noOutgoing) EdgeFollowing(key STKey) *Edge {
-	panic("Leaf has no children")
+	return nil
 }
 
 func (node *noOutgoing) AddOutgoingEdgeNode(key STKey, edge *Edge, n Node) {
@@ -145,11 +155,11 @@ func (node *noOutgoing) AddOutgoingEdgeNode(key STKey, edge *Edge, n Node) {
 }
 
 func (node *noOutgoing) outgoingEdgeNode(key STKey) (*Edge, Node) {
-	panic("Leaf has no outgoing edges or nodes")
+	return nil, nil
 }
 
 func (node *noOutgoing) NodeFollowing(key STKey) Node {
-	panic("Leaf has no child nodes")
+	return nil
 }
 
 func (node *noOutgoing) removeEdgeFollowing(key STKey) {
@@ -225,7 +235,7 @@ func (node *noIncomingEdge) depth() int32 {
 	return 0
 }
 
-// only Internal Nodes have suffix links
+// Internal Nodes get suffix links during construction, Leaves when the tree is finished
 type hasSuffixLink struct {
 	_suffixLink Node
 }
@@ -238,14 +248,17 @@ func (internal *hasSuffixLink) SetSuffixLink(slink Node) {
 	internal._suffixLink = slink
 }
 
-type noSuffixLink struct{}
+// the Root links to itself, there is no shorter suffix
+type selfSuffixLink struct {
+	self Node
+}
 
-func (nsl *noSuffixLink) SuffixLink() Node {
-	return nil
+func (ssl *selfSuffixLink) SuffixLink() Node {
+	return ssl.self
 }
 
-func (nsl *noSuffixLink) SetSuffixLink(node Node) {
-	panic("Node does NOT have a suffix link")
+// the Root's suffix link cannot be changed
+func (ssl *selfSuffixLink) SetSuffixLink(node Node) {
 }
 
 // Root Node
@@ -253,15 +266,17 @@ type rootNode struct {
 	hasId
 	hasOutgoing
 	noIncomingEdge
-	noSuffixLink
+	selfSuffixLink
 }
 
 func NewRootNode(id int32) Node {
-	return &rootNode{
+	root := &rootNode{
 		hasId{id},
 		hasOutgoing{make(map[STKey]*Edge), make(map[STKey]Node)},
 		noIncomingEdge{},
-		noSuffixLink{}}
+		selfSuffixLink{}}
+	root.self = root
+	return root
 }
 
 func (root *rootNode) String() string {
@@ -341,7 +356,7 @@ type leafNode struct {
 	hasId
 	noOutgoing
 	hasIncomingEdge
-	noSuffixLink
+	hasSuffixLink
 	_suffixOffset int32
 }
 
@@ -351,7 +366,7 @@ func NewLeafEdgeNode(id int32, parent Node, suffix int32) (*Edge, Node) {
 		hasId{id},
 		noOutgoing{},
 		hasIncomingEdge{parent, leafEdge},
-		noSuffixLink{}, suffix - parent.depth()}
+		hasSuffixLink{nil}, suffix - parent.depth()}
 }
 
 func (leaf *leafNode) String() string {
@@ -370,6 +385,11 @@ func (leaf *leafNode) SuffixOffset() int32 {
 	return leaf._suffixOffset
 }
 
+// a leaf in a sliding window can be reused for a different suffix
+func (leaf *leafNode) setSuffixOffset(offset int32) {
+	leaf._suffixOffset = offset
+}
+
 func (leaf *leafNode) ChildSuffixes(result []int32) []int32 {
 	return append(result, leaf.SuffixOffset())
 }
@@ -0,0 +1,117 @@
+package suffixtree
+
+import (
+	"sort"
+	"sync"
+)
+
+// OnlineTree extends a tree on one goroutine while others query it.
+//
+// Until Finish is called the tree is implicit: the suffixes starting at offsets
+// NumberLeaves()..offset-1 end inside an edge instead of at a leaf.  Searches look
+// for those directly, and only report occurrences that end at or before the offset
+// the search is pinned to, so results never depend on how far the builder has got.
+type OnlineTree struct {
+	mutex   sync.RWMutex
+	builder *ukkonen
+}
+
+func NewOnlineTree(dataSource DataSource) *OnlineTree {
+	return &OnlineTree{builder: NewUkkonen(dataSource).(*ukkonen)}
+}
+
+// Extend adds the next value to the tree, it returns false once the data channel is closed.
+// Only one goroutine should call Extend and Finish.
+func (o *OnlineTree) Extend() bool {
+	// wait for the value without holding the lock, so readers are not blocked by a slow source
+	value, ok := <-o.builder.dataChannel
+	if !ok {
+		return false
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.builder.extendValue(value)
+	return true
+}
+
+// Finish adds the Terminator, making every suffix explicit
+func (o *OnlineTree) Finish() error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	return o.builder.Finish()
+}
+
+// Offset is the number of values added to the tree so far
+func (o *OnlineTree) Offset() int32 {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	return o.builder.NumberValuesLoaded()
+}
+
+// View runs fn with the tree locked against extension
+func (o *OnlineTree) View(fn func(tree SuffixTree, offset int32)) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	fn(o.builder.Tree(), o.builder.NumberValuesLoaded())
+}
+
+// Find searches the values added so far
+func (o *OnlineTree) Find(sequence []STKey) ([]int32, error) {
+	return o.Snapshot().Find(sequence)
+}
+
+// Snapshot returns a Searcher pinned to the current offset, later extensions do not change its results
+func (o *OnlineTree) Snapshot() Searcher {
+	return &onlineSearcher{o, o.Offset()}
+}
+
+type onlineSearcher struct {
+	tree   *OnlineTree
+	offset int32
+}
+
+func (s *onlineSearcher) Find(sequence []STKey) ([]int32, error) {
+	s.tree.mutex.RLock()
+	defer s.tree.mutex.RUnlock()
+	return s.tree.builder.findBefore(sequence, s.offset)
+}
+
+// find the occurrences of sequence that end before offset end, in a tree that may still be implicit
+func (b *ukkonen) findBefore(sequence []STKey, end int32) ([]int32, error) {
+	length := int32(len(sequence))
+
+	// occurrences at suffixes that already have a leaf
+	found, err := NewSearcher(b.root, b.dataSource).Find(sequence)
+	if err != nil {
+		return nil, err
+	}
+	result := int32arr{}
+	for _, offset := range found {
+		if offset+length <= end {
+			result = append(result, offset)
+		}
+	}
+
+	// implicit suffixes have no leaf, compare them directly
+	for offset := b.numberLeaves; offset+length <= end; offset++ {
+		matches, err := b.matchesAt(offset, sequence)
+		if err != nil {
+			return nil, err
+		}
+		if matches {
+			result = append(result, offset)
+		}
+	}
+	sort.Sort(result)
+	return result, nil
+}
+
+func (b *ukkonen) matchesAt(offset int32, sequence []STKey) (bool, error) {
+	for i, value := range sequence {
+		key, err := keyAt(b.dataSource, offset+int32(i))
+		if err != nil || key != value {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,61 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// the offsets, in values, where pattern occurs in s
+func occurrencesIn(s, pattern string) []int32 {
+	values, sequence := []rune(s), []rune(pattern)
+	found := []int32{}
+	for i := 0; i+len(sequence) <= len(values); i++ {
+		if string(values[i:i+len(sequence)]) == pattern {
+			found = append(found, int32(i))
+		}
+	}
+	return found
+}
+
+func sameOffsets(a, b []int32) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+// a snapshot taken while another goroutine extends the tree finds what is in the data up to its offset
+func TestOnlineSnapshots(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		alphabet := []string{"a", "ab", "abc"}[i%3]
+		s := randomString(r, 1+r.Intn(80), alphabet)
+		online := NewOnlineTree(NewStringDataSource(s))
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for online.Extend() {
+			}
+		}()
+		for q := 0; q < 50; q++ {
+			snapshot := online.Snapshot()
+			offset := snapshot.(*onlineSearcher).offset
+			pattern := randomString(r, 1+r.Intn(4), alphabet)
+			found, err := snapshot.Find(stringKeys(pattern))
+			if want := occurrencesIn(s[:offset], pattern); err != nil || !sameOffsets(found, want) {
+				t.Fatalf("%q at %d: Find(%q) = %v, %v, want %v", s, offset, pattern, found, err, want)
+			}
+		}
+		wg.Wait()
+		if err := online.Finish(); err != nil {
+			t.Fatal(err)
+		}
+		for _, pattern := range []string{alphabet[:1], strings.Repeat(alphabet[:1], 2), alphabet} {
+			found, err := online.Find(stringKeys(pattern))
+			if want := occurrencesIn(s, pattern); err != nil || !sameOffsets(found, want) {
+				t.Errorf("%q finished: Find(%q) = %v, %v, want %v", s, pattern, found, err, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package suffixtree
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions control BuildParallel, a nil *ParallelOptions uses the defaults
+type ParallelOptions struct {
+	// suffixes are partitioned by their first PrefixLength values, defaults to 2
+	PrefixLength int
+	// number of partitions built at once, defaults to GOMAXPROCS
+	Workers int
+}
+
+const defaultPrefixLength = 2
+
+// BuildParallel builds the same tree as Build, using several cores.
+//
+// The suffix array (SA-IS) and LCP array of the data are computed first, both in linear time.
+// Suffixes sharing their leading k-mer are then a run of the suffix array, the runs are built
+// into subtrees concurrently and merged under the root.  The whole data source is read into
+// memory first, a byte per value when the values fit in a byte, and the tree's edges still
+// refer to the data source.
+func BuildParallel(ctx context.Context, dataSource DataSource, opts *ParallelOptions) (SuffixTree, error) {
+	return Build(ctx, dataSource, &BuildOptions{Algorithm: ParallelAlgorithm, Parallel: opts})
+}
+
+func buildParallel(ctx context.Context, factory *idFactory, text builderText, opts *ParallelOptions) (Node, error) {
+	prefixLength, workers := defaultPrefixLength, runtime.GOMAXPROCS(0)
+	if opts != nil && opts.PrefixLength > 0 {
+		prefixLength = opts.PrefixLength
+	}
+	if opts != nil && opts.Workers > 0 {
+		workers = opts.Workers
+	}
+
+	suffixes := suffixArray(text)
+	lcps := lcpArray(text, suffixes)
+	partitions := partitionSuffixes(lcps, int32(prefixLength))
+	subtrees := make([]Node, len(partitions))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				subtrees[i] = buildPartition(factory, text, suffixes, lcps, partitions[i])
+			}
+		}()
+	}
+sending:
+	for i := range partitions {
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break sending
+		}
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	root := NewRootNode(factory.NextId())
+	for _, subtree := range subtrees {
+		mergeChildren(factory, text, root, subtree)
+	}
+	return root, setSuffixLinks(root, text)
+}
+
+// a run of the suffix array, from start up to (not including) end
+type partition struct {
+	start, end int
+}
+
+// split the suffix array into runs of suffixes sharing their first prefixLength values.  A suffix
+// shorter than that shares less than prefixLength values with any other, so it is a run of its own.
+func partitionSuffixes(lcps []int32, prefixLength int32) []partition {
+	partitions := []partition{}
+	start := 0
+	for i := 1; i <= len(lcps); i++ {
+		if i == len(lcps) || lcps[i] < prefixLength {
+			partitions = append(partitions, partition{start, i})
+			start = i
+		}
+	}
+	return partitions
+}
+
+// build one partition's suffixes into a subtree, the first of them shares nothing with the suffix
+// before it in the subtree
+func buildPartition(factory *idFactory, text builderText, suffixes, lcps []int32, p partition) Node {
+	partitionLCPs := make([]int32, p.end-p.start)
+	copy(partitionLCPs[1:], lcps[p.start+1:p.end])
+	return buildFromSorted(factory, text, suffixes[p.start:p.end], partitionLCPs)
+}
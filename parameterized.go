@@ -0,0 +1,395 @@
+package suffixtree
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// A PToken is one value of a parameterized string (Baker's p-string).  A fixed token only matches
+// itself, a parameter matches any parameter that is used consistently: two runs of tokens p-match
+// when one turns into the other by renaming its parameters one to one, as when identifiers are renamed.
+type PToken struct {
+	Value     STKey // fixed tokens are 0 or more, a parameter's Value names it
+	Parameter bool
+}
+
+// A ParameterizedTree is the suffix tree of the prev-encodings of the suffixes of a p-string.
+//
+// The prev-encoding replaces each parameter by the distance back to the previous use of the same
+// parameter in the suffix, or marks it as the first use.  Two runs p-match exactly when their
+// prev-encodings are equal.  Since a parameter's encoding depends on where its suffix starts, the
+// encodings are not suffixes of each other: edge labels are read relative to the suffix of a leaf
+// below, and the node for an encoding without its first value may not exist (Baker).
+type ParameterizedTree struct {
+	tokens   []PToken
+	previous []int32 // the offset of the previous use of each parameter, -1 for fixed tokens and first uses
+	next     []int32 // and of the next use, -1 if there is none
+	root     Node
+	first    map[Node]int32 // the smallest suffix below each internal node
+}
+
+// the encoding of the end of the tokens, which follows every suffix
+const pEnd STKey = math.MinInt32
+
+// ErrFixedToken is returned for a fixed PToken with a negative Value
+var ErrFixedToken = errors.New("suffixtree: fixed tokens must not be negative")
+
+// BuildParameterized builds the tree of the tokens' suffixes
+func BuildParameterized(tokens []PToken) (*ParameterizedTree, error) {
+	n := int32(len(tokens))
+	t := &ParameterizedTree{tokens: tokens, previous: make([]int32, n), next: make([]int32, n)}
+	last := make(map[STKey]int32)
+	for offset, token := range tokens {
+		t.previous[offset], t.next[offset] = -1, -1
+		if !token.Parameter {
+			if token.Value < 0 {
+				return nil, ErrFixedToken
+			}
+			continue
+		}
+		if previous, ok := last[token.Value]; ok {
+			t.previous[offset] = previous
+			t.next[previous] = int32(offset)
+		}
+		last[token.Value] = int32(offset)
+	}
+	t.root = newParameterizedBuilder(t).build()
+	t.first = firstOffsets(t.root, n+1)
+	return t, nil
+}
+
+// parameterizedBuilder inserts the suffixes longest first, as McCreight's algorithm does.  The end
+// follows every suffix, so each one gets a leaf.
+//
+// A suffix that p-matches an earlier one for some length still does without its first token, so
+// each head is at most one shallower than the one before.  The suffix link of an internal node
+// leads to the deepest node on the path of its suffixes without their first token.  Dropping the
+// token turns a later use of a parameter into a first use, so suffixes that differ below the node
+// can agree without it, and that path may end inside an edge.  The next head is found by following
+// the link and skipping down to the known depth, then comparing value by value.
+type parameterizedBuilder struct {
+	*ParameterizedTree
+	factory *idFactory
+	depths  map[Node]int32 // the depth of each internal node
+	below   map[Node]int32 // a suffix below each internal node, its edge labels are read from it
+}
+
+func newParameterizedBuilder(t *ParameterizedTree) *parameterizedBuilder {
+	return &parameterizedBuilder{t, NewNodeIdFactory(), make(map[Node]int32), make(map[Node]int32)}
+}
+
+func (b *parameterizedBuilder) build() Node {
+	n := int32(len(b.tokens))
+	root := NewRootNode(b.factory.NextId())
+	b.depths[root] = 0
+	head, headDepth := root, int32(0)
+	for suffix := int32(0); suffix <= n; suffix++ {
+		from, known := root, int32(0)
+		if !head.isRoot() {
+			known = headDepth - 1
+			if parent := head.parent(); !parent.isRoot() {
+				from = parent.SuffixLink()
+			}
+			from = b.skip(from, suffix, known)
+			head.SetSuffixLink(from)
+		}
+		head, headDepth = b.scan(from, suffix, known)
+		edge, leaf := newLeafForSuffix(b.factory.NextId(), head, suffix+headDepth, suffix)
+		head.AddOutgoingEdgeNode(b.encoding(suffix, headDepth), edge, leaf)
+	}
+	return root
+}
+
+// a suffix below a node, and how deep the node is
+func (b *parameterizedBuilder) suffixAndDepth(node Node) (int32, int32) {
+	if node.IsLeaf() {
+		return node.SuffixOffset(), int32(len(b.tokens)) + 1 - node.SuffixOffset()
+	}
+	return b.below[node], b.depths[node]
+}
+
+// the deepest node on the suffix's path from node that is no deeper than the suffix is known to match
+func (b *parameterizedBuilder) skip(node Node, suffix, known int32) Node {
+	for depth := b.depths[node]; depth < known; {
+		_, child := node.outgoingEdgeNode(b.encoding(suffix, depth))
+		if child.IsLeaf() {
+			break
+		}
+		childDepth := b.depths[child]
+		if childDepth > known {
+			break
+		}
+		node, depth = child, childDepth
+	}
+	return node
+}
+
+// scan down from node, the suffix matching at least known values, returning the node where the
+// suffix leaves the tree and its depth, splitting an edge if needed
+func (b *parameterizedBuilder) scan(node Node, suffix, known int32) (Node, int32) {
+	depth := b.depths[node]
+	for {
+		_, child := node.outgoingEdgeNode(b.encoding(suffix, depth))
+		if child == nil {
+			return node, depth
+		}
+		childSuffix, childDepth := b.suffixAndDepth(child)
+		matched := depth + 1
+		if known > matched {
+			matched = known
+		}
+		if matched > childDepth {
+			matched = childDepth
+		}
+		for matched < childDepth && b.encoding(childSuffix, matched) == b.encoding(suffix, matched) {
+			matched++
+		}
+		if matched == childDepth {
+			// a leaf ends with the end of the tokens, which no other suffix has at that depth
+			node, depth = child, childDepth
+			continue
+		}
+		return b.split(node, child, childSuffix, depth, matched), matched
+	}
+}
+
+// split the edge from parent (at depth) to child after the child's suffix's first splitDepth values
+func (b *parameterizedBuilder) split(parent, child Node, childSuffix, depth, splitDepth int32) Node {
+	topEdge := child.IncomingEdge()
+	bottomEdge := NewEdge(topEdge.StartOffset+splitDepth-depth, topEdge.EndOffset)
+	topEdge.EndOffset = bottomEdge.StartOffset - 1
+	internal := NewInternalNode(b.factory.NextId(), parent, topEdge)
+	parent.AddOutgoingEdgeNode(b.encoding(childSuffix, depth), topEdge, internal)
+	internal.AddOutgoingEdgeNode(b.encoding(childSuffix, splitDepth), bottomEdge, child)
+	child.setIncoming(internal, bottomEdge)
+	b.depths[internal], b.below[internal] = splitDepth, childSuffix
+	return internal
+}
+
+// the encoding of the token depth values into the suffix at offset
+func (t *ParameterizedTree) encoding(suffix, depth int32) STKey {
+	offset := suffix + depth
+	if offset == int32(len(t.tokens)) {
+		return pEnd
+	}
+	token := t.tokens[offset]
+	if !token.Parameter {
+		return token.Value
+	}
+	if previous := t.previous[offset]; previous >= suffix {
+		return STKey(-1 - (offset - previous))
+	}
+	return -1
+}
+
+// the prev-encoding of a pattern
+func encodePattern(pattern []PToken) []STKey {
+	encoded := make([]STKey, len(pattern))
+	last := make(map[STKey]int)
+	for i, token := range pattern {
+		if !token.Parameter {
+			encoded[i] = token.Value
+			continue
+		}
+		encoded[i] = -1
+		if previous, ok := last[token.Value]; ok {
+			encoded[i] = STKey(-1 - (i - previous))
+		}
+		last[token.Value] = i
+	}
+	return encoded
+}
+
+func (t *ParameterizedTree) Root() Node {
+	return t.root
+}
+
+func (t *ParameterizedTree) NumberTokens() int32 {
+	return int32(len(t.tokens))
+}
+
+// a suffix below node
+func (t *ParameterizedTree) suffixBelow(node Node) int32 {
+	if node.IsLeaf() {
+		return node.SuffixOffset()
+	}
+	return t.first[node]
+}
+
+// Find returns the sorted offsets where the pattern p-matches the tokens
+func (t *ParameterizedTree) Find(pattern []PToken) []int32 {
+	encoded := encodePattern(pattern)
+	node, depth := t.root, int32(0)
+	for depth < int32(len(encoded)) {
+		child := node.outgoingNodeMap()[encoded[depth]]
+		if child == nil {
+			return []int32{}
+		}
+		suffix := t.suffixBelow(child)
+		end := int32(len(t.tokens)) + 1 - suffix
+		if !child.IsLeaf() {
+			end = depth + child.IncomingEdge().length()
+		}
+		for ; depth < end && depth < int32(len(encoded)); depth++ {
+			if t.encoding(suffix, depth) != encoded[depth] {
+				return []int32{}
+			}
+		}
+		node = child
+	}
+	result := int32arr(node.ChildSuffixes(nil))
+	sort.Sort(result)
+	return result
+}
+
+// A Clone is two runs of Length tokens that p-match, at offsets A < B.  The runs do not overlap,
+// and cannot be extended in either direction without losing the match.
+type Clone struct {
+	A, B   int32
+	Length int32
+}
+
+// What comes before a suffix decides whether a p-matching pair of runs extends to the left: both
+// runs must follow the same fixed token, or follow parameters whose next uses inside the runs are
+// at the same places, the first next use deciding it.  Runs at the start of the tokens follow nothing.
+type leftContext struct {
+	parameter bool
+	value     int32 // the fixed token, or where the parameter is used next, -1 for not at all
+}
+
+var startContext = leftContext{false, -1}
+
+// the left contexts of the suffixes below a node.  A parameter's next use is kept while it is
+// inside the node's runs, the ones that are not yet are popped off later as the runs get shorter.
+type leftContexts struct {
+	suffixes map[leftContext][]int32
+	pending  []int32 // a max-heap of the next uses kept, deepest first
+	size     int
+}
+
+func (c *leftContexts) add(context leftContext, suffixes []int32) {
+	if _, ok := c.suffixes[context]; !ok && context.parameter && context.value >= 0 {
+		c.pending = append(c.pending, context.value)
+		for i := len(c.pending) - 1; i > 0 && c.pending[(i-1)/2] < c.pending[i]; i = (i - 1) / 2 {
+			c.pending[i], c.pending[(i-1)/2] = c.pending[(i-1)/2], c.pending[i]
+		}
+	}
+	c.suffixes[context] = append(c.suffixes[context], suffixes...)
+	c.size += len(suffixes)
+}
+
+// forget the next uses at length or beyond, they are outside runs of that length
+func (c *leftContexts) shorten(length int32) {
+	for len(c.pending) > 0 && c.pending[0] >= length {
+		context := leftContext{true, c.pending[0]}
+		last := len(c.pending) - 1
+		c.pending[0] = c.pending[last]
+		c.pending = c.pending[:last]
+		for i := 0; ; {
+			largest := i
+			for _, child := range []int{2*i + 1, 2*i + 2} {
+				if child < last && c.pending[child] > c.pending[largest] {
+					largest = child
+				}
+			}
+			if largest == i {
+				break
+			}
+			c.pending[i], c.pending[largest] = c.pending[largest], c.pending[i]
+			i = largest
+		}
+		suffixes := c.suffixes[context]
+		delete(c.suffixes, context)
+		c.size -= len(suffixes)
+		c.add(leftContext{true, -1}, suffixes)
+	}
+}
+
+// Clones returns the clones of at least minLength tokens, ordered by A then B.
+//
+// Suffixes below different children of a node p-match for the node's depth and differ after it,
+// a pair of them is a clone if the runs do not overlap and their left contexts differ.  The
+// suffixes below each node are grouped by left context (Gusfield's maximal pairs), so pairs that
+// extend to the left are never looked at.
+func (t *ParameterizedTree) Clones(minLength int32) []Clone {
+	if minLength < 1 {
+		minLength = 1
+	}
+	n := int32(len(t.tokens))
+	clones := []Clone{}
+	var visit func(node Node, depth int32) *leftContexts
+	visit = func(node Node, depth int32) *leftContexts {
+		if node.IsLeaf() {
+			suffix := node.SuffixOffset()
+			if suffix == n {
+				return nil
+			}
+			contexts := &leftContexts{suffixes: make(map[leftContext][]int32)}
+			context := startContext
+			if suffix > 0 {
+				before := t.tokens[suffix-1]
+				context = leftContext{before.Parameter, int32(before.Value)}
+				if before.Parameter {
+					context.value = -1
+					if next := t.next[suffix-1]; next >= 0 {
+						context.value = next - suffix
+					}
+				}
+			}
+			contexts.add(context, []int32{suffix})
+			return contexts
+		}
+		var below *leftContexts
+		for _, child := range node.OutgoingNodes() {
+			childDepth := depth
+			if !child.IsLeaf() {
+				childDepth += child.IncomingEdge().length()
+			}
+			contexts := visit(child, childDepth)
+			if depth < minLength || contexts == nil {
+				// the runs above are too short to be clones
+				continue
+			}
+			contexts.shorten(depth)
+			if below == nil {
+				below = contexts
+				continue
+			}
+			for context, suffixes := range contexts.suffixes {
+				for otherContext, others := range below.suffixes {
+					if context == otherContext && context != startContext {
+						continue
+					}
+					for _, x := range suffixes {
+						for _, y := range others {
+							a, b := x, y
+							if a > b {
+								a, b = b, a
+							}
+							if a+depth <= b {
+								clones = append(clones, Clone{a, b, depth})
+							}
+						}
+					}
+				}
+			}
+			// merge the smaller groups into the larger
+			if contexts.size > below.size {
+				below, contexts = contexts, below
+			}
+			for context, suffixes := range contexts.suffixes {
+				below.add(context, suffixes)
+			}
+		}
+		return below
+	}
+	visit(t.root, 0)
+	sort.Slice(clones, func(i, j int) bool {
+		if clones[i].A != clones[j].A {
+			return clones[i].A < clones[j].A
+		}
+		return clones[i].B < clones[j].B
+	})
+	return clones
+}
@@ -0,0 +1,166 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// whether a and b are the same but for a one to one renaming of their parameters
+func pMatch(a, b []PToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	aToB, bToA := map[STKey]STKey{}, map[STKey]STKey{}
+	for i := range a {
+		if a[i].Parameter != b[i].Parameter {
+			return false
+		}
+		if !a[i].Parameter {
+			if a[i].Value != b[i].Value {
+				return false
+			}
+			continue
+		}
+		if value, ok := aToB[a[i].Value]; ok && value != b[i].Value {
+			return false
+		}
+		if value, ok := bToA[b[i].Value]; ok && value != a[i].Value {
+			return false
+		}
+		aToB[a[i].Value], bToA[b[i].Value] = b[i].Value, a[i].Value
+	}
+	return true
+}
+
+// few distinct values, so that there are many p-matches
+func randomPTokens(r *rand.Rand, n int) []PToken {
+	tokens := make([]PToken, n)
+	for i := range tokens {
+		if r.Intn(2) == 0 {
+			tokens[i] = PToken{STKey(r.Intn(2)), false}
+		} else {
+			tokens[i] = PToken{STKey(r.Intn(3)), true}
+		}
+	}
+	return tokens
+}
+
+// the offsets where pattern p-matches tokens, comparing every window
+func bruteFind(tokens, pattern []PToken) []int32 {
+	offsets := []int32{}
+	for i := 0; i+len(pattern) <= len(tokens); i++ {
+		if pMatch(tokens[i:i+len(pattern)], pattern) {
+			offsets = append(offsets, int32(i))
+		}
+	}
+	return offsets
+}
+
+// the clones found by extending every pair of offsets as far as they p-match
+func bruteClones(tokens []PToken, minLength int32) []Clone {
+	n := int32(len(tokens))
+	clones := []Clone{}
+	for a := int32(0); a < n; a++ {
+		for b := a + 1; b < n; b++ {
+			length := int32(0)
+			for b+length < n && pMatch(tokens[a:a+length+1], tokens[b:b+length+1]) {
+				length++
+			}
+			if length < minLength || a+length > b {
+				continue
+			}
+			if a > 0 && pMatch(tokens[a-1:a+length], tokens[b-1:b+length]) {
+				continue
+			}
+			clones = append(clones, Clone{a, b, length})
+		}
+	}
+	return clones
+}
+
+func TestParameterizedFindMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(12))
+	for i := 0; i < 300; i++ {
+		tokens := randomPTokens(r, r.Intn(40))
+		tree, err := BuildParameterized(tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tree.NumberTokens() != int32(len(tokens)) {
+			t.Fatalf("%d tokens, tree has %d", len(tokens), tree.NumberTokens())
+		}
+		for j := 0; j < 20; j++ {
+			pattern := randomPTokens(r, r.Intn(5))
+			if j%2 == 1 && len(tokens) > 0 {
+				// a renamed run of the tokens, so that long patterns are found too
+				start := r.Intn(len(tokens))
+				pattern = append([]PToken{}, tokens[start:start+r.Intn(len(tokens)-start)+1]...)
+				for k := range pattern {
+					if pattern[k].Parameter {
+						pattern[k].Value += 10
+					}
+				}
+			}
+			got, want := tree.Find(pattern), bruteFind(tokens, pattern)
+			if !reflect.DeepEqual([]int32(got), want) {
+				t.Fatalf("Find(%v) in %v is %v, want %v", pattern, tokens, got, want)
+			}
+		}
+	}
+}
+
+func TestParameterizedClonesMatchBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(13))
+	for i := 0; i < 300; i++ {
+		tokens := randomPTokens(r, r.Intn(40))
+		tree, err := BuildParameterized(tokens)
+		if err != nil {
+			t.Fatal(err)
+		}
+		minLength := int32(1 + r.Intn(4))
+		got, want := tree.Clones(minLength), bruteClones(tokens, minLength)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("Clones(%d) of %v is\n%v, want\n%v", minLength, tokens, got, want)
+		}
+	}
+}
+
+func TestParameterizedClonesOfRenamedCopy(t *testing.T) {
+	// x = y + x; z = y, then the same with x, y and z renamed, after a fixed token
+	original := []PToken{{0, true}, {1, false}, {1, true}, {2, false}, {0, true}, {3, false}, {2, true}, {1, false}, {1, true}}
+	tokens := append(append([]PToken{}, original...), PToken{9, false})
+	for _, token := range original {
+		if token.Parameter {
+			token.Value += 5
+		}
+		tokens = append(tokens, token)
+	}
+	tree, err := BuildParameterized(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Clone{{0, 10, 9}}
+	if got := tree.Clones(5); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Clones(5) = %v, want %v", got, want)
+	}
+}
+
+func TestBuildParameterizedLongStream(t *testing.T) {
+	if testing.Short() {
+		t.Skip("long stream")
+	}
+	r := rand.New(rand.NewSource(14))
+	tokens := randomPTokens(r, 200000)
+	tree, err := BuildParameterized(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		start := r.Intn(len(tokens) - 20)
+		pattern := tokens[start : start+20]
+		if got, want := tree.Find(pattern), bruteFind(tokens, pattern); !reflect.DeepEqual([]int32(got), want) {
+			t.Fatalf("Find(%v) is %v, want %v", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package suffixtree
+
+import "math"
+
+// A Predictor is a variable order Markov model of the data in a finished tree: the values that
+// follow a context, and how often, are the children of the context's location in the tree.
+//
+// Probabilities are estimated as in PPM (method C, without exclusions): the longest context that
+// occurs predicts first, escaping to the next shorter context through suffix links for values it
+// has never seen followed by.  The end of the data is not predicted, a '$' in the data is.
+type Predictor struct {
+	root        Node
+	dataSource  DataSource
+	traverser   Traverser
+	occurrences *occurrences
+	alphabet    int // distinct values in the data
+}
+
+// A Prediction is the values that follow the longest part of a context occurring in the data
+type Prediction struct {
+	Order  int32 // values of the context used, the longest suffix of it that occurs
+	Counts map[STKey]int32
+	Total  int32
+}
+
+// NewPredictor counts the occurrences below every node of a finished tree, the tree must not change after
+func NewPredictor(tree SuffixTree) *Predictor {
+	p := &Predictor{
+		root:        tree.Root(),
+		dataSource:  tree.DataSource(),
+		traverser:   NewTraverser(tree.DataSource()),
+		occurrences: countOccurrences(tree),
+	}
+	for _, edge := range p.root.OutgoingEdgeMap() {
+		if !p.occurrences.terminal(edge) {
+			p.alphabet++
+		}
+	}
+	return p
+}
+
+// the values following a location order values deep, with the number of times each does
+func (p *Predictor) following(location *Location, order int32) (map[STKey]int32, error) {
+	result := make(map[STKey]int32)
+	if location.OnNode {
+		for key, child := range location.Base.outgoingNodeMap() {
+			if !p.occurrences.terminal(child.IncomingEdge()) {
+				result[key] = p.occurrences.at(child, order+1)
+			}
+		}
+		return result, nil
+	}
+	offset := location.Base.IncomingEdge().StartOffset + location.OffsetFromTop
+	if offset >= p.occurrences.length {
+		return result, nil
+	}
+	key, err := keyAt(p.dataSource, offset)
+	if err != nil {
+		return nil, err
+	}
+	result[key] = p.occurrences.at(location.Base, order+1)
+	return result, nil
+}
+
+// move to the next shorter context, false if the location is at the root already
+func (p *Predictor) shorten(location *Location, order *int32) bool {
+	if location.OnNode && location.Base.isRoot() {
+		return false
+	}
+	p.traverser.traverseToNextSuffix(location)
+	*order--
+	return true
+}
+
+// move to the longest suffix of the context followed by value that occurs
+func (p *Predictor) extend(location *Location, order *int32, value STKey) error {
+	for {
+		found, err := p.traverser.traverseDownValue(location, value)
+		if err != nil {
+			return err
+		}
+		if found {
+			*order++
+			return nil
+		}
+		if !p.shorten(location, order) {
+			return nil
+		}
+	}
+}
+
+// the location and length of the longest suffix of context that occurs
+func (p *Predictor) locate(context []STKey) (*Location, int32, error) {
+	location, order := NewLocation(p.root), int32(0)
+	for _, value := range context {
+		if err := p.extend(location, &order, value); err != nil {
+			return nil, 0, err
+		}
+	}
+	return location, order, nil
+}
+
+// Predict returns the values following the longest suffix of context that is followed by a value
+// in the data.  A suffix occurring only at the end of the data escapes to a shorter one.
+func (p *Predictor) Predict(context []STKey) (Prediction, error) {
+	location, order, err := p.locate(context)
+	if err != nil {
+		return Prediction{}, err
+	}
+	counts, err := p.following(location, order)
+	for err == nil && len(counts) == 0 && p.shorten(location, &order) {
+		counts, err = p.following(location, order)
+	}
+	if err != nil {
+		return Prediction{}, err
+	}
+	prediction := Prediction{Order: order, Counts: counts}
+	for _, count := range prediction.Counts {
+		prediction.Total += count
+	}
+	return prediction, nil
+}
+
+// the probability of value following the location, escaping to shorter contexts
+func (p *Predictor) probability(location Location, order int32, value STKey) (float64, error) {
+	escape := 1.0
+	for {
+		counts, err := p.following(&location, order)
+		if err != nil {
+			return 0, err
+		}
+		total := int32(0)
+		for _, count := range counts {
+			total += count
+		}
+		distinct := float64(len(counts))
+		if count, ok := counts[value]; ok {
+			return escape * float64(count) / (float64(total) + distinct), nil
+		}
+		if total > 0 {
+			escape *= distinct / (float64(total) + distinct)
+		}
+		if !p.shorten(&location, &order) {
+			break
+		}
+	}
+	// a value never seen, as likely as any other
+	return escape / float64(p.alphabet+1), nil
+}
+
+// Probability returns the estimated probability of value following context
+func (p *Predictor) Probability(context []STKey, value STKey) (float64, error) {
+	location, order, err := p.locate(context)
+	if err != nil {
+		return 0, err
+	}
+	return p.probability(*location, order, value)
+}
+
+// LogLikelihood returns the natural log of the probability of the sequence, each value predicted
+// from the values before it.  Low scores per value mark sequences unlike the data.
+func (p *Predictor) LogLikelihood(sequence []STKey) (float64, error) {
+	location, order := NewLocation(p.root), int32(0)
+	sum := 0.0
+	for _, value := range sequence {
+		probability, err := p.probability(*location, order, value)
+		if err != nil {
+			return 0, err
+		}
+		sum += math.Log(probability)
+		if err := p.extend(location, &order, value); err != nil {
+			return 0, err
+		}
+	}
+	return sum, nil
+}
@@ -0,0 +1,97 @@
+package suffixtree
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// the values following each occurrence of context in s, with their counts
+func followingValues(s, context string) map[STKey]int32 {
+	counts := make(map[STKey]int32)
+	text := []rune(s)
+	values := []rune(context)
+	for i := 0; i+len(values) < len(text); i++ {
+		if string(text[i:i+len(values)]) == context {
+			counts[STKey(text[i+len(values)])]++
+		}
+	}
+	return counts
+}
+
+// the longest suffix of context that is followed by a value in s, or that occurs in s
+func longestContext(s, context string, followed bool) int {
+	for length := len(context); length > 0; length-- {
+		suffix := context[len(context)-length:]
+		if (followed && len(followingValues(s, suffix)) > 0) || (!followed && strings.Contains(s, suffix)) {
+			return length
+		}
+	}
+	return 0
+}
+
+func TestPredictMatchesBruteForce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		alphabet := []string{"abc", "ab$", "a$"}[i%3]
+		s := randomString(r, 1+r.Intn(30), alphabet)
+		p := NewPredictor(buildWith(t, s, Algorithm(i%4)))
+		values := 0
+		for _, value := range alphabet {
+			if strings.ContainsRune(s, value) {
+				values++
+			}
+		}
+		for j := 0; j < 20; j++ {
+			context := randomString(r, r.Intn(6), alphabet)
+			prediction, err := p.Predict(stringKeys(context))
+			if err != nil {
+				t.Fatalf("Predict(%q) on %q: %v", context, s, err)
+			}
+			order := longestContext(s, context, true)
+			want := followingValues(s, context[len(context)-order:])
+			if int(prediction.Order) != order || !reflect.DeepEqual(prediction.Counts, want) {
+				t.Fatalf("Predict(%q) on %q = %+v, want order %d with %v", context, s, prediction, order, want)
+			}
+
+			// PPM method C, escaping from the longest context that occurs
+			value := STKey(alphabet[r.Intn(len(alphabet))])
+			escape, probability := 1.0, -1.0
+			for length := longestContext(s, context, false); length >= 0 && probability < 0; length-- {
+				counts := followingValues(s, context[len(context)-length:])
+				total := int32(0)
+				for _, count := range counts {
+					total += count
+				}
+				if count, ok := counts[value]; ok {
+					probability = escape * float64(count) / float64(int(total)+len(counts))
+				} else if total > 0 {
+					escape *= float64(len(counts)) / float64(int(total)+len(counts))
+				}
+			}
+			if probability < 0 {
+				probability = escape / float64(values+1)
+			}
+			got, err := p.Probability(stringKeys(context), value)
+			if err != nil || math.Abs(got-probability) > 1e-12 {
+				t.Fatalf("Probability(%q, %c) on %q = %v, %v, want %v", context, value, s, got, err, probability)
+			}
+		}
+	}
+}
+
+func TestPredictDollarInData(t *testing.T) {
+	p := NewPredictor(buildString(t, "abb$abbbb"))
+	prediction, err := p.Predict(stringKeys("b"))
+	want := map[STKey]int32{'b': 4, '$': 1}
+	if err != nil || prediction.Order != 1 || !reflect.DeepEqual(prediction.Counts, want) || prediction.Total != 5 {
+		t.Errorf("Predict(\"b\") = %+v, %v", prediction, err)
+	}
+	// "c" only ends the data, so the prediction escapes to the empty context
+	prediction, err = NewPredictor(buildString(t, "abc")).Predict(stringKeys("c"))
+	if err != nil || prediction.Order != 0 || prediction.Total != 3 {
+		t.Errorf("Predict(\"c\") = %+v, %v", prediction, err)
+	}
+}
@@ -0,0 +1,59 @@
+package suffixtree
+
+import "sort"
+
+// A Repeat is a maximal repeat: a run of values occurring more than once, where the occurrences
+// are followed by different values and preceded by different values (or the start of the data),
+// so it cannot be extended in either direction without losing an occurrence.
+type Repeat struct {
+	Length  int32
+	Offsets []int32 // in increasing order
+}
+
+// Repeats returns the maximal repeats of at least minLength values in a finished tree,
+// longest first.
+//
+// Every internal node is followed by different values, it is a maximal repeat when the values
+// before its occurrences are not all the same.
+func Repeats(tree SuffixTree, minLength int32) []Repeat {
+	dataSource := tree.DataSource()
+	repeats := []Repeat{}
+	// returns the value before every suffix below node, and false if they differ
+	var precedingValue func(node Node, depth int32) (STKey, bool)
+	precedingValue = func(node Node, depth int32) (STKey, bool) {
+		if node.IsLeaf() {
+			if node.SuffixOffset() == 0 {
+				return 0, false
+			}
+			return dataSource.KeyAtOffset(node.SuffixOffset() - 1), true
+		}
+		var preceding STKey
+		same, first := true, true
+		for _, child := range node.OutgoingNodes() {
+			childDepth := depth
+			if !child.IsLeaf() {
+				childDepth += child.IncomingEdge().length()
+			}
+			value, childSame := precedingValue(child, childDepth)
+			if !childSame || (!first && value != preceding) {
+				same = false
+			}
+			preceding, first = value, false
+		}
+		if !same && !node.isRoot() && depth >= minLength {
+			offsets := node.ChildSuffixes(nil)
+			sort.Sort(int32arr(offsets))
+			repeats = append(repeats, Repeat{depth, offsets})
+		}
+		return preceding, same
+	}
+	precedingValue(tree.Root(), 0)
+
+	sort.Slice(repeats, func(i, j int) bool {
+		if repeats[i].Length != repeats[j].Length {
+			return repeats[i].Length > repeats[j].Length
+		}
+		return repeats[i].Offsets[0] < repeats[j].Offsets[0]
+	})
+	return repeats
+}
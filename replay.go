@@ -0,0 +1,221 @@
+package suffixtree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+// A Recorder is a Tracer that keeps every construction event, so the construction can be
+// inspected or replayed phase by phase.  A phase adds one value, extending every suffix with it.
+//
+//	u := NewUkkonen(dataSource)
+//	recorder := NewRecorder()
+//	u.Trace(recorder)
+//	u.DrainDataSourceContext(ctx)
+//	u.Finish()
+//	recorder.WriteTimeline(w)
+type Recorder struct {
+	phases   []Phase
+	location LocationState
+}
+
+// A Phase is the events of adding the value at Offset, starting with its ExtendStarted
+type Phase struct {
+	Offset  int32
+	Value   STKey
+	Start   LocationState // the active point before the value is added
+	End     LocationState // and after
+	Created []int32       // ids of the nodes created, leaves and internal nodes
+	Events  []Event
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) Trace(event Event) {
+	switch e := event.(type) {
+	case ExtendStarted:
+		if n := len(r.phases); n > 0 {
+			r.phases[n-1].End = e.Location
+		}
+		r.phases = append(r.phases, Phase{Offset: e.Offset, Value: e.Value, Start: e.Location})
+		r.location = e.Location
+	case LeafCreated:
+		r.current().Created = append(r.current().Created, e.Leaf)
+	case EdgeSplit:
+		r.current().Created = append(r.current().Created, e.Node)
+	case LocationMoved:
+		r.location = e.Location
+	}
+	if phase := r.current(); phase != nil {
+		phase.Events = append(phase.Events, event)
+	}
+}
+
+func (r *Recorder) current() *Phase {
+	if len(r.phases) == 0 {
+		return nil
+	}
+	return &r.phases[len(r.phases)-1]
+}
+
+// Phases returns the phases recorded so far
+func (r *Recorder) Phases() []Phase {
+	phases := make([]Phase, len(r.phases))
+	copy(phases, r.phases)
+	if n := len(phases); n > 0 {
+		phases[n-1].End = r.location
+	}
+	return phases
+}
+
+// the attributes of an event as a JSON object, with its name as "event"
+func eventObject(event Event) map[string]interface{} {
+	var object func(attrs []slog.Attr) map[string]interface{}
+	object = func(attrs []slog.Attr) map[string]interface{} {
+		result := make(map[string]interface{}, len(attrs)+1)
+		for _, attr := range attrs {
+			if attr.Value.Kind() == slog.KindGroup {
+				result[attr.Key] = object(attr.Value.Group())
+			} else {
+				result[attr.Key] = attr.Value.Any()
+			}
+		}
+		return result
+	}
+	result := object(event.Attrs())
+	result["event"] = event.Name()
+	return result
+}
+
+// WriteTimeline writes the phases as a JSON document, each phase with its events in order
+func (r *Recorder) WriteTimeline(w io.Writer) error {
+	type jsonPhase struct {
+		Offset  int32                    `json:"offset"`
+		Value   STKey                    `json:"value"`
+		Start   map[string]interface{}   `json:"start"`
+		End     map[string]interface{}   `json:"end"`
+		Created []int32                  `json:"created"`
+		Events  []map[string]interface{} `json:"events"`
+	}
+	location := func(state LocationState) map[string]interface{} {
+		return eventObject(LocationMoved{Location: state})["location"].(map[string]interface{})
+	}
+	timeline := struct {
+		Phases []jsonPhase `json:"phases"`
+	}{[]jsonPhase{}}
+	for _, phase := range r.Phases() {
+		p := jsonPhase{phase.Offset, phase.Value, location(phase.Start), location(phase.End), phase.Created,
+			make([]map[string]interface{}, len(phase.Events))}
+		if p.Created == nil {
+			p.Created = []int32{}
+		}
+		for i, event := range phase.Events {
+			p.Events[i] = eventObject(event)
+		}
+		timeline.Phases = append(timeline.Phases, p)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(timeline)
+}
+
+// the data known after a phase: leaf edges end at the phase's value, where the Terminator would be
+type phaseDataSource struct {
+	DataSource
+	length int64
+}
+
+func (p phaseDataSource) Len() int64 {
+	return p.length
+}
+
+// Replay rebuilds the tree from the recorded events, calling snapshot after each phase with the
+// tree as it was at the end of that phase.  The tree is changed by the next phase, so snapshot
+// should not keep it.  The Recorder must have traced the construction from its first value.
+// Leaf suffix links, set by Finish, are not part of the replay.
+func (r *Recorder) Replay(dataSource DataSource, snapshot func(phase Phase, tree SuffixTree) error) error {
+	phases := r.Phases()
+	if len(phases) == 0 {
+		return nil
+	}
+	root := NewRootNode(phases[0].Start.Node)
+	nodes := map[int32]Node{root.Id(): root}
+	find := func(id int32) (Node, error) {
+		if node, ok := nodes[id]; ok {
+			return node, nil
+		}
+		return nil, fmt.Errorf("%w: replay refers to node %d before it was created", ErrMalformedTree, id)
+	}
+
+	for _, phase := range phases {
+		for _, event := range phase.Events {
+			switch e := event.(type) {
+			case LeafCreated:
+				parent, err := find(e.Parent)
+				if err != nil {
+					return err
+				}
+				edge, leaf := newLeafForSuffix(e.Leaf, parent, e.Edge.StartOffset, e.Suffix)
+				parent.AddOutgoingEdgeNode(dataSource.KeyAtOffset(e.Edge.StartOffset), edge, leaf)
+				nodes[e.Leaf] = leaf
+			case EdgeSplit:
+				parent, err := find(e.Parent)
+				if err != nil {
+					return err
+				}
+				child, err := find(e.Child)
+				if err != nil {
+					return err
+				}
+				top, bottom := e.Top, e.Bottom
+				internal := NewInternalNode(e.Node, parent, &top)
+				parent.AddOutgoingEdgeNode(dataSource.KeyAtOffset(top.StartOffset), &top, internal)
+				internal.AddOutgoingEdgeNode(dataSource.KeyAtOffset(bottom.StartOffset), &bottom, child)
+				child.setIncoming(internal, &bottom)
+				nodes[e.Node] = internal
+			case SuffixLinkSet:
+				from, err := find(e.From)
+				if err != nil {
+					return err
+				}
+				to, err := find(e.To)
+				if err != nil {
+					return err
+				}
+				from.SetSuffixLink(to)
+			}
+		}
+		view := phaseDataSource{dataSource, int64(phase.Offset)}
+		if err := snapshot(phase, NewSuffixTree(root, view)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteSnapshots writes the tree after each phase in DOT format, to the writer create returns
+// for that phase.  The nodes created in the phase and the node at the active point are filled in.
+func (r *Recorder) WriteSnapshots(dataSource DataSource, create func(phase Phase) (io.WriteCloser, error), opts *ExportOptions) error {
+	return r.Replay(dataSource, func(phase Phase, tree SuffixTree) error {
+		phaseOpts := ExportOptions{}
+		if opts != nil {
+			phaseOpts = *opts
+		}
+		phaseOpts.Title = fmt.Sprintf("phase %d: %s added, active point %s", phase.Offset,
+			dataSource.StringFrom(phase.Offset, phase.Offset), phase.End)
+		phaseOpts.Highlight = append(append([]int32{}, phase.Created...), phase.End.Node)
+		w, err := create(phase)
+		if err != nil {
+			return err
+		}
+		if err := WriteDOT(w, tree, &phaseOpts); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	})
+}
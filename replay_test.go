@@ -0,0 +1,104 @@
+package suffixtree
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+type closingBuffer struct {
+	*bytes.Buffer
+}
+
+func (closingBuffer) Close() error { return nil }
+
+func recordBuild(t *testing.T, s string) (SuffixTree, *Recorder) {
+	t.Helper()
+	recorder := NewRecorder()
+	tree, err := Build(context.Background(), NewStringDataSource(s), &BuildOptions{Tracer: recorder})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, recorder
+}
+
+// after each phase the replayed tree is the implicit tree of the values up to it
+func TestReplay(t *testing.T) {
+	for _, s := range []string{"", "a", "abcabxabcdab", "mississippi", "aaaaaa"} {
+		tree, recorder := recordBuild(t, s)
+		if phases := recorder.Phases(); len(phases) != len(s)+1 {
+			t.Fatalf("%q: recorded %d phases", s, len(phases))
+		}
+		var last SuffixTree
+		err := recorder.Replay(tree.DataSource(), func(phase Phase, replayed SuffixTree) error {
+			if int(phase.Offset) < len(s) {
+				prefix := NewStringDataSource(s[:phase.Offset+1])
+				implicit, err := Build(context.Background(), prefix, &BuildOptions{NoFinish: true})
+				if err != nil {
+					return err
+				}
+				if err := Equivalent(implicit, NewSuffixTree(replayed.Root(), prefix)); err != nil {
+					t.Errorf("%q after phase %d: %v", s, phase.Offset, err)
+				}
+			}
+			last = replayed
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Equivalent(tree, last); err != nil {
+			t.Errorf("%q: the replay ends with a different tree: %v", s, err)
+		}
+	}
+}
+
+func TestWriteTimeline(t *testing.T) {
+	_, recorder := recordBuild(t, "abcab")
+	var out bytes.Buffer
+	if err := recorder.WriteTimeline(&out); err != nil {
+		t.Fatal(err)
+	}
+	var timeline struct {
+		Phases []struct {
+			Offset int32                    `json:"offset"`
+			Events []map[string]interface{} `json:"events"`
+		} `json:"phases"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &timeline); err != nil {
+		t.Fatalf("%v in %s", err, out.String())
+	}
+	if len(timeline.Phases) != 6 {
+		t.Fatalf("%d phases in %s", len(timeline.Phases), out.String())
+	}
+	for i, phase := range timeline.Phases {
+		if phase.Offset != int32(i) || len(phase.Events) == 0 || phase.Events[0]["event"] != "ExtendStarted" {
+			t.Errorf("phase %d: %+v", i, phase)
+		}
+	}
+}
+
+func TestWriteSnapshots(t *testing.T) {
+	tree, recorder := recordBuild(t, "abcab")
+	snapshots := []*bytes.Buffer{}
+	err := recorder.WriteSnapshots(tree.DataSource(), func(phase Phase) (io.WriteCloser, error) {
+		snapshot := &bytes.Buffer{}
+		snapshots = append(snapshots, snapshot)
+		return closingBuffer{snapshot}, nil
+	}, nil)
+	if err != nil || len(snapshots) != 6 {
+		t.Fatalf("%d snapshots, %v", len(snapshots), err)
+	}
+	for i, snapshot := range snapshots {
+		if !strings.HasPrefix(snapshot.String(), "digraph suffixtree {") || !strings.Contains(snapshot.String(), "fillcolor") {
+			t.Errorf("snapshot %d:\n%s", i, snapshot.String())
+		}
+	}
+	// the last phase adds the Terminator, which gives every suffix its leaf
+	if leaves := strings.Count(snapshots[5].String(), "shape=box"); leaves != 6 {
+		t.Errorf("%d leaves after the last phase:\n%s", leaves, snapshots[5].String())
+	}
+}
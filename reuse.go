@@ -0,0 +1,207 @@
+package suffixtree
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A ReuseReport lists the pairs of documents sharing passages, see TextReuse
+type ReuseReport struct {
+	MinLength int32
+	Pairs     []DocumentPair
+}
+
+// A DocumentPair is two documents, A < B, and the passages they share
+type DocumentPair struct {
+	A, B int
+	// percentage of each document's values inside one of the passages
+	CoverageA, CoverageB float64
+	Passages             []Passage
+}
+
+// A Passage occurs in both documents of a pair, and is not part of a longer passage at those offsets
+type Passage struct {
+	OffsetA int32 // offsets within each document
+	OffsetB int32
+	Length  int32
+	Text    string // the passage's values as bytes, as read from a file
+}
+
+// the value before a suffix, the start of the data differing from every value
+type leftValue struct {
+	value STKey
+	start bool
+}
+
+// TextReuse reports every pair of documents sharing a passage of at least minLength values, for a
+// tree built over a GeneralizedDataSource with a sequence for each document.
+//
+// The passages are the maximal pairs between documents, found in one bottom-up pass over the tree.
+// Two suffixes below different children of a node share the node's path and differ after it.
+// When the values before them differ too, the path is a passage that cannot be extended.  A highly
+// repetitive collection has many such pairs, minLength should be long enough to leave out chance matches.
+func TextReuse(tree SuffixTree, minLength int32) (ReuseReport, error) {
+	g, ok := tree.DataSource().(*GeneralizedDataSource)
+	if !ok {
+		return ReuseReport{}, errors.New("suffixtree: text reuse needs a tree built over a GeneralizedDataSource")
+	}
+	if minLength < 1 {
+		minLength = 1
+	}
+	type pairKey struct{ a, b int }
+	pairs := make(map[pairKey]*DocumentPair)
+	addPassage := func(x, y, length int32) {
+		a, offsetA := g.SequenceAt(x)
+		b, offsetB := g.SequenceAt(y)
+		if a == b {
+			return
+		}
+		if a > b {
+			a, b, offsetA, offsetB = b, a, offsetB, offsetA
+		}
+		pair := pairs[pairKey{a, b}]
+		if pair == nil {
+			pair = &DocumentPair{A: a, B: b}
+			pairs[pairKey{a, b}] = pair
+		}
+		start := g.SequenceStart(a) + offsetA
+		pair.Passages = append(pair.Passages, Passage{offsetA, offsetB, length, bytesAt(g, start, length)})
+	}
+
+	// the suffixes below a node by the value before them, for nodes at least minLength deep
+	var visit func(node Node, depth int32) map[leftValue][]int32
+	visit = func(node Node, depth int32) map[leftValue][]int32 {
+		if node.IsLeaf() {
+			offset := node.SuffixOffset()
+			if i, _ := g.SequenceAt(offset); i < 0 {
+				return nil
+			}
+			left := leftValue{start: true}
+			if offset > 0 {
+				left = leftValue{value: g.KeyAtOffset(offset - 1)}
+			}
+			return map[leftValue][]int32{left: {offset}}
+		}
+		var below map[leftValue][]int32
+		for _, child := range node.OutgoingNodes() {
+			childDepth := depth
+			if !child.IsLeaf() {
+				childDepth += child.IncomingEdge().length()
+			}
+			suffixes := visit(child, childDepth)
+			if depth < minLength {
+				continue
+			}
+			if len(suffixes) > len(below) {
+				below, suffixes = suffixes, below
+			}
+			if below == nil {
+				below = suffixes
+				continue
+			}
+			for left, offsets := range suffixes {
+				for otherLeft, otherOffsets := range below {
+					if left == otherLeft {
+						continue
+					}
+					for _, x := range offsets {
+						for _, y := range otherOffsets {
+							addPassage(x, y, depth)
+						}
+					}
+				}
+			}
+			for left, offsets := range suffixes {
+				below[left] = append(below[left], offsets...)
+			}
+		}
+		return below
+	}
+	visit(tree.Root(), 0)
+
+	report := ReuseReport{MinLength: minLength, Pairs: make([]DocumentPair, 0, len(pairs))}
+	for _, pair := range pairs {
+		sort.Slice(pair.Passages, func(i, j int) bool {
+			p, q := pair.Passages[i], pair.Passages[j]
+			if p.OffsetA != q.OffsetA {
+				return p.OffsetA < q.OffsetA
+			}
+			return p.OffsetB < q.OffsetB
+		})
+		pair.CoverageA = coverage(pair.Passages, g.SequenceLength(pair.A), func(p Passage) int32 { return p.OffsetA })
+		pair.CoverageB = coverage(pair.Passages, g.SequenceLength(pair.B), func(p Passage) int32 { return p.OffsetB })
+		report.Pairs = append(report.Pairs, *pair)
+	}
+	sort.Slice(report.Pairs, func(i, j int) bool {
+		p, q := report.Pairs[i], report.Pairs[j]
+		if p.A != q.A {
+			return p.A < q.A
+		}
+		return p.B < q.B
+	})
+	return report, g.Err()
+}
+
+// the values at offset, one byte each
+func bytesAt(dataSource DataSource, offset, length int32) string {
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = byte(dataSource.KeyAtOffset(offset + int32(i)))
+	}
+	return string(text)
+}
+
+// the percentage of length values inside the union of the passages
+func coverage(passages []Passage, length int32, offset func(p Passage) int32) float64 {
+	if length == 0 {
+		return 0
+	}
+	starts := make([]int32, len(passages))
+	for i, passage := range passages {
+		starts[i] = offset(passage)
+	}
+	order := make([]int, len(passages))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return starts[order[i]] < starts[order[j]] })
+	covered, end := int32(0), int32(0)
+	for _, i := range order {
+		start, stop := starts[i], starts[i]+passages[i].Length
+		if start < end {
+			start = end
+		}
+		if stop > start {
+			covered += stop - start
+			end = stop
+		}
+	}
+	return 100 * float64(covered) / float64(length)
+}
+
+// Write prints each pair of documents with its coverage, then its passages one per line.
+// Documents are named by names, or numbered if names is nil.
+func (report ReuseReport) Write(w io.Writer, names []string) error {
+	name := func(i int) string {
+		if i < len(names) {
+			return names[i]
+		}
+		return fmt.Sprintf("document %d", i)
+	}
+	for _, pair := range report.Pairs {
+		_, err := fmt.Fprintf(w, "%s (%.1f%%) and %s (%.1f%%): %d passages\n",
+			name(pair.A), pair.CoverageA, name(pair.B), pair.CoverageB, len(pair.Passages))
+		if err != nil {
+			return err
+		}
+		for _, passage := range pair.Passages {
+			_, err := fmt.Fprintf(w, "\t%d\t%d\t%d\t%q\n", passage.OffsetA, passage.OffsetB, passage.Length, passage.Text)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package suffixtree
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func bytesKeys(s string) []STKey {
+	keys := make([]STKey, len(s))
+	for i := 0; i < len(s); i++ {
+		keys[i] = STKey(s[i])
+	}
+	return keys
+}
+
+// documents read from files hold bytes, the passages print as the text they came from
+func TestTextReuseBytes(t *testing.T) {
+	dataSource := NewGeneralizedDataSource(bytesKeys("le café noir"), bytesKeys("un café noir"))
+	tree, err := Build(context.Background(), dataSource, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := TextReuse(tree, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Pairs) != 1 || len(report.Pairs[0].Passages) != 1 {
+		t.Fatalf("TextReuse found %+v", report.Pairs)
+	}
+	if passage := report.Pairs[0].Passages[0]; passage.Text != " café noir" || passage.OffsetA != 2 || passage.OffsetB != 2 {
+		t.Errorf("passage %+v", passage)
+	}
+	var written bytes.Buffer
+	if err := report.Write(&written, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(written.String(), `" café noir"`) {
+		t.Errorf("report %q", written.String())
+	}
+}
@@ -1,9 +1,22 @@
 package suffixtree
 
-import "sort"
+import (
+	"fmt"
+	"sort"
+)
 
 type Searcher interface {
-	Find(sequence []STKey) (suffixOffsets []int32)
+	Find(sequence []STKey) (suffixOffsets []int32, err error)
+}
+
+// A Counter counts the occurrences of a sequence without listing them
+type Counter interface {
+	Count(sequence []STKey) (int, error)
+}
+
+// A PrefixMatcher finds the longest prefix of a sequence that occurs in the data
+type PrefixMatcher interface {
+	LongestPrefix(sequence []STKey) (length int32, suffixOffsets []int32, err error)
 }
 
 type searcher struct {
@@ -12,26 +25,225 @@ type searcher struct {
 	traverser  Traverser
 }
 
+// the Searcher is also a Counter and a PrefixMatcher
 func NewSearcher(root Node, dataSource DataSource) Searcher {
 	return &searcher{root, dataSource, NewTraverser(dataSource)}
 }
 
+func NewPrefixMatcher(root Node, dataSource DataSource) PrefixMatcher {
+	return &searcher{root, dataSource, NewTraverser(dataSource)}
+}
+
 type int32arr []int32
 
 func (a int32arr) Len() int           { return len(a) }
 func (a int32arr) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a int32arr) Less(i, j int) bool { return a[i] < a[j] }
 
-func (s *searcher) Find(sequence []STKey) []int32 {
+func (s *searcher) Find(sequence []STKey) ([]int32, error) {
 	result := int32arr{}
 	location := NewLocation(s.root)
 	for _, val := range sequence {
-		if !s.traverser.traverseDownValue(location, val) {
-			return result
+		found, err := s.traverser.traverseDownValue(location, val)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return result, nil
 		}
 	}
 
-	result = location.Base.ChildSuffixes(result)
+	result, err := leafOffsets(location.Base, result)
+	if err != nil {
+		return nil, err
+	}
 	sort.Sort(result)
-	return result
+	return result, nil
+}
+
+func (s *searcher) Count(sequence []STKey) (int, error) {
+	location := NewLocation(s.root)
+	for _, val := range sequence {
+		found, err := s.traverser.traverseDownValue(location, val)
+		if err != nil {
+			return 0, err
+		}
+		if !found {
+			return 0, nil
+		}
+	}
+	if location.Base.IsLeaf() {
+		return 1, nil
+	}
+	count := 0
+	nodes := []Node{location.Base}
+	for len(nodes) > 0 {
+		node := nodes[len(nodes)-1]
+		nodes = nodes[:len(nodes)-1]
+		for _, child := range node.OutgoingNodes() {
+			if child.IsLeaf() {
+				count++
+			} else {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return count, nil
+}
+
+// LongestPrefix returns the length of the longest prefix of sequence found in the tree, and the
+// sorted offsets where it occurs (none when no value of the sequence matches)
+func (s *searcher) LongestPrefix(sequence []STKey) (int32, []int32, error) {
+	location := NewLocation(s.root)
+	length := int32(0)
+	for _, val := range sequence {
+		found, err := s.traverser.traverseDownValue(location, val)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !found {
+			break
+		}
+		length++
+	}
+	if length == 0 {
+		return 0, int32arr{}, nil
+	}
+
+	result, err := leafOffsets(location.Base, int32arr{})
+	if err != nil {
+		return 0, nil, err
+	}
+	sort.Sort(int32arr(result))
+	return length, result, nil
+}
+
+// the smallest suffix offset below each internal node, n for a node with no leaves below
+func firstOffsets(root Node, n int32) map[Node]int32 {
+	first := make(map[Node]int32)
+	var smallest func(node Node) int32
+	smallest = func(node Node) int32 {
+		if node.IsLeaf() {
+			return node.SuffixOffset()
+		}
+		result := n
+		for _, child := range node.OutgoingNodes() {
+			if offset := smallest(child); offset < result {
+				result = offset
+			}
+		}
+		first[node] = result
+		return result
+	}
+	smallest(root)
+	return first
+}
+
+// The occurrences of the paths in a finished tree.  A suffix that also occurs earlier followed by
+// a '$' in the data is implicit: it has no leaf, its path ends on an edge.  A path occurs once for
+// each leaf below it and once for each implicit suffix ending at or below it.
+type occurrences struct {
+	length int32            // the offset of the Terminator
+	below  map[Node]int32   // the leaves and implicit suffixes below each node's incoming edge
+	ending map[Node][]int32 // the sorted depths of the implicit suffixes ending on each node's incoming edge
+}
+
+func countOccurrences(tree SuffixTree) *occurrences {
+	root, dataSource := tree.Root(), tree.DataSource()
+	o := &occurrences{below: make(map[Node]int32), ending: make(map[Node][]int32)}
+	hasLeaf := make(map[int32]bool)
+	// a malformed tree is counted as far as its leaves can be found
+	offsets, _ := leafOffsets(root, nil)
+	for _, offset := range offsets {
+		hasLeaf[offset] = true
+		if offset > o.length {
+			o.length = offset
+		}
+	}
+	if sized, ok := dataSource.(lengthKnown); ok {
+		o.length = int32(sized.Len())
+	}
+
+	// the implicit suffixes are the last ones, each is found from the one before through its suffix link
+	traverser := NewTraverser(dataSource)
+	var location *Location
+	for suffix := int32(0); suffix < o.length; suffix++ {
+		if hasLeaf[suffix] {
+			location = nil
+			continue
+		}
+		if location != nil {
+			traverser.traverseToNextSuffix(location)
+		} else {
+			location = NewLocation(root)
+			for offset := suffix; offset < o.length && location != nil; offset++ {
+				value, err := keyAt(dataSource, offset)
+				if err != nil {
+					location = nil
+					break
+				}
+				if found, err := traverser.traverseDownValue(location, value); !found || err != nil {
+					location = nil
+				}
+			}
+			if location == nil {
+				continue
+			}
+		}
+		o.ending[location.Base] = append(o.ending[location.Base], o.length-suffix)
+	}
+	if !hasLeaf[o.length] {
+		// the data ends with a '$', so the empty suffix has no leaf either
+		o.ending[root] = append(o.ending[root], 0)
+	}
+	for _, depths := range o.ending {
+		sort.Slice(depths, func(i, j int) bool { return depths[i] < depths[j] })
+	}
+
+	var count func(node Node) int32
+	count = func(node Node) int32 {
+		result := int32(1)
+		if !node.IsLeaf() {
+			result = 0
+			for _, child := range node.OutgoingNodes() {
+				result += count(child)
+			}
+		}
+		o.below[node] = result
+		return result + int32(len(o.ending[node]))
+	}
+	count(root)
+	return o
+}
+
+// the occurrences of the path of depth values ending at node, or on the edge above it
+func (o *occurrences) at(node Node, depth int32) int32 {
+	ending := o.ending[node]
+	return o.below[node] + int32(len(ending)-sort.Search(len(ending), func(i int) bool { return ending[i] >= depth }))
+}
+
+// whether an edge holds only the Terminator, past the end of the data
+func (o *occurrences) terminal(edge *Edge) bool {
+	return edge.StartOffset >= o.length
+}
+
+// collect the suffix offsets of the leaves at or below node, reporting nil children
+// and childless internal nodes instead of following them
+func leafOffsets(node Node, result []int32) ([]int32, error) {
+	if node.IsLeaf() {
+		return append(result, node.SuffixOffset()), nil
+	}
+	if node.NumberOutgoing() == 0 {
+		return result, fmt.Errorf("%w: internal node %d has no children", ErrMalformedTree, node.Id())
+	}
+	var err error
+	for key, child := range node.outgoingNodeMap() {
+		if child == nil {
+			return result, fmt.Errorf("%w: node %d has a nil child for value %d", ErrMalformedTree, node.Id(), key)
+		}
+		if result, err = leafOffsets(child, result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
 }
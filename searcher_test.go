@@ -0,0 +1,97 @@
+package suffixtree
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the finished tree of s, built with Ukkonen's algorithm
+func buildString(t *testing.T, s string) SuffixTree {
+	t.Helper()
+	u := NewUkkonen(NewStringDataSource(s))
+	for u.Extend() {
+	}
+	if err := u.Finish(); err != nil {
+		t.Fatalf("building %q: %v", s, err)
+	}
+	return u.Tree()
+}
+
+func stringKeys(s string) []STKey {
+	keys := []STKey{}
+	for _, r := range s {
+		keys = append(keys, STKey(r))
+	}
+	return keys
+}
+
+func TestFindPastTheEnd(t *testing.T) {
+	tree := buildString(t, "abab")
+	searcher := NewSearcher(tree.Root(), tree.DataSource())
+	for _, pattern := range []string{"b$x", "b$", "abab$", "$"} {
+		found, err := searcher.Find(stringKeys(pattern))
+		if err != nil || len(found) != 0 {
+			t.Errorf("Find(%q) = %v, %v, the Terminator is not a value", pattern, found, err)
+		}
+	}
+	// earlier misses do not turn into errors for later queries
+	found, err := searcher.Find(stringKeys("ab"))
+	if err != nil || !reflect.DeepEqual(found, []int32{0, 2}) {
+		t.Errorf("Find(\"ab\") = %v, %v", found, err)
+	}
+	found, err = searcher.Find(stringKeys("b"))
+	if err != nil || !reflect.DeepEqual(found, []int32{1, 3}) {
+		t.Errorf("Find(\"b\") = %v, %v", found, err)
+	}
+	if err := sourceErr(tree.DataSource()); err != nil {
+		t.Errorf("queries recorded an error in the data source: %v", err)
+	}
+}
+
+func TestFindDollarInData(t *testing.T) {
+	tree := buildString(t, "ab$ab")
+	found, err := NewSearcher(tree.Root(), tree.DataSource()).Find(stringKeys("b$"))
+	if err != nil || !reflect.DeepEqual(found, []int32{1}) {
+		t.Errorf("Find(\"b$\") = %v, %v", found, err)
+	}
+}
+
+// a DataSource written outside the package, with no Err method
+type plainDataSource struct {
+	values []STKey
+}
+
+func (p *plainDataSource) KeyAtOffset(offset int32) STKey {
+	if int(offset) == len(p.values) {
+		return Terminator
+	}
+	return p.values[offset]
+}
+
+func (p *plainDataSource) STKeys() <-chan STKey {
+	values := make(chan STKey)
+	go func() {
+		for _, value := range p.values {
+			values <- value
+		}
+		close(values)
+	}()
+	return values
+}
+
+func (p *plainDataSource) StringFrom(start, end int32) string          { return "" }
+func (p *plainDataSource) StringFromTo(start int32, end string) string { return "" }
+
+func TestDataSourceWithoutErr(t *testing.T) {
+	dataSource := &plainDataSource{stringKeys("abab")}
+	u := NewUkkonen(dataSource)
+	for u.Extend() {
+	}
+	if err := u.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	found, err := NewSearcher(u.Tree().Root(), dataSource).Find(stringKeys("ab"))
+	if err != nil || !reflect.DeepEqual(found, []int32{0, 2}) {
+		t.Errorf("Find(\"ab\") = %v, %v", found, err)
+	}
+}
@@ -0,0 +1,339 @@
+// Package server answers queries against a suffix tree over HTTP, with JSON responses.
+//
+// Every endpoint takes GET requests:
+//
+//	/find?q=pattern&offset=0&limit=100     the offsets where a pattern occurs, a page at a time
+//	/count?q=pattern                       the number of occurrences of a pattern
+//	/prefix?q=pattern&offset=0&limit=100   the longest prefix of a pattern that occurs, and where
+//	/repeats?min=2&offset=0&limit=100      the maximal repeats, longest first
+//
+// Offsets are offsets into the tree's data source.  For a tree built over a GeneralizedDataSource
+// they are offsets within a sequence instead, and responses list the sequence of each offset.
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jojohannsen/suffixtree"
+)
+
+// Options control the Handler, a nil *Options uses the defaults
+type Options struct {
+	// requests taking longer are answered with 503 Service Unavailable, defaults to 10 seconds
+	Timeout time.Duration
+	// page size when a request has no limit, defaults to 100
+	DefaultLimit int
+	// largest page size a request may ask for, defaults to 10000
+	MaxLimit int
+	// values are Unicode code points rather than bytes, both in queries and in returned text
+	Runes bool
+}
+
+const (
+	defaultTimeout      = 10 * time.Second
+	defaultLimit        = 100
+	defaultMaxLimit     = 10000
+	defaultRepeatLength = 2
+)
+
+type handler struct {
+	tree      suffixtree.SuffixTree
+	opts      Options
+	searcher  suffixtree.Searcher
+	matcher   suffixtree.PrefixMatcher
+	sequences *suffixtree.GeneralizedDataSource
+
+	// the repeats of the most recent minimum length asked for
+	repeatsMutex  sync.Mutex
+	repeatsLength int32
+	repeats       []suffixtree.Repeat
+}
+
+// NewHandler serves queries against a finished tree.  The tree is only read, so any number
+// of requests can be answered at once.
+func NewHandler(tree suffixtree.SuffixTree, opts *Options) http.Handler {
+	h := &handler{
+		tree:     tree,
+		searcher: suffixtree.NewSearcher(tree.Root(), tree.DataSource()),
+		matcher:  suffixtree.NewPrefixMatcher(tree.Root(), tree.DataSource()),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.Timeout <= 0 {
+		h.opts.Timeout = defaultTimeout
+	}
+	if h.opts.DefaultLimit <= 0 {
+		h.opts.DefaultLimit = defaultLimit
+	}
+	if h.opts.MaxLimit <= 0 {
+		h.opts.MaxLimit = defaultMaxLimit
+	}
+	h.sequences, _ = tree.DataSource().(*suffixtree.GeneralizedDataSource)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/find", h.get(h.find))
+	mux.HandleFunc("/count", h.get(h.count))
+	mux.HandleFunc("/prefix", h.get(h.prefix))
+	mux.HandleFunc("/repeats", h.get(h.repeatsPage))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint %s", r.URL.Path))
+	})
+	timeoutBody, _ := json.Marshal(errorResponse{"request timed out"})
+	return http.TimeoutHandler(mux, h.opts.Timeout, string(timeoutBody))
+}
+
+// a query's status: 400 for a bad request, 500 for a failed one
+type queryError struct {
+	status int
+	err    error
+}
+
+func (e *queryError) Error() string {
+	return e.err.Error()
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &queryError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// wrap a query as a GET handler writing its result or error as JSON
+func (h *handler) get(query func(r *http.Request) (interface{}, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("%s is not allowed", r.Method))
+			return
+		}
+		result, err := query(r)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if qe, ok := err.(*queryError); ok {
+				status = qe.status
+			}
+			writeError(w, status, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// the q parameter as values, an empty pattern would match at every offset and is refused
+func (h *handler) pattern(r *http.Request) (string, []suffixtree.STKey, error) {
+	pattern := r.URL.Query().Get("q")
+	if pattern == "" {
+		return "", nil, badRequest("missing or empty parameter q")
+	}
+	values := []suffixtree.STKey{}
+	if h.opts.Runes {
+		for _, r := range pattern {
+			values = append(values, suffixtree.STKey(r))
+		}
+	} else {
+		for i := 0; i < len(pattern); i++ {
+			values = append(values, suffixtree.STKey(pattern[i]))
+		}
+	}
+	return pattern, values, nil
+}
+
+// the text of length values at offset
+func (h *handler) text(offset, length int32) string {
+	dataSource := h.tree.DataSource()
+	if h.opts.Runes {
+		runes := make([]rune, length)
+		for i := range runes {
+			runes[i] = rune(dataSource.KeyAtOffset(offset + int32(i)))
+		}
+		return string(runes)
+	}
+	bytes := make([]byte, length)
+	for i := range bytes {
+		bytes[i] = byte(dataSource.KeyAtOffset(offset + int32(i)))
+	}
+	return string(bytes)
+}
+
+func intParameter(r *http.Request, name string, defaultValue, min, max int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < min || n > max {
+		return 0, badRequest("parameter %s must be a number from %d to %d", name, min, max)
+	}
+	return n, nil
+}
+
+// A Page is one part of a longer list of results
+type Page struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+// the page the offset and limit parameters select from total results
+func (h *handler) page(r *http.Request, total int) (Page, int, int, error) {
+	offset, err := intParameter(r, "offset", 0, 0, int(^uint(0)>>1))
+	if err != nil {
+		return Page{}, 0, 0, err
+	}
+	limit, err := intParameter(r, "limit", h.opts.DefaultLimit, 1, h.opts.MaxLimit)
+	if err != nil {
+		return Page{}, 0, 0, err
+	}
+	start, end := offset, offset+limit
+	if start > total {
+		start = total
+	}
+	if end > total || end < 0 {
+		end = total
+	}
+	return Page{total, offset, limit}, start, end, nil
+}
+
+// Occurrences are offsets into the data, with the sequence of each one for generalized trees
+type Occurrences struct {
+	Offsets   []int32 `json:"offsets"`
+	Sequences []int   `json:"sequences,omitempty"`
+}
+
+func (h *handler) occurrences(offsets []int32) Occurrences {
+	if h.sequences == nil {
+		return Occurrences{Offsets: offsets}
+	}
+	result := Occurrences{make([]int32, len(offsets)), make([]int, len(offsets))}
+	for i, offset := range offsets {
+		result.Sequences[i], result.Offsets[i] = h.sequences.SequenceAt(offset)
+	}
+	return result
+}
+
+type FindResponse struct {
+	Pattern string `json:"pattern"`
+	Page
+	Occurrences
+}
+
+func (h *handler) find(r *http.Request) (interface{}, error) {
+	pattern, values, err := h.pattern(r)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := h.searcher.Find(values)
+	if err != nil {
+		return nil, err
+	}
+	page, start, end, err := h.page(r, len(offsets))
+	if err != nil {
+		return nil, err
+	}
+	return FindResponse{pattern, page, h.occurrences(offsets[start:end])}, nil
+}
+
+type CountResponse struct {
+	Pattern string `json:"pattern"`
+	Count   int    `json:"count"`
+}
+
+func (h *handler) count(r *http.Request) (interface{}, error) {
+	pattern, values, err := h.pattern(r)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := h.searcher.Find(values)
+	if err != nil {
+		return nil, err
+	}
+	return CountResponse{pattern, len(offsets)}, nil
+}
+
+type PrefixResponse struct {
+	Pattern string `json:"pattern"`
+	Length  int32  `json:"length"` // values of the pattern matched, 0 if none
+	Match   string `json:"match"`
+	Page
+	Occurrences
+}
+
+func (h *handler) prefix(r *http.Request) (interface{}, error) {
+	pattern, values, err := h.pattern(r)
+	if err != nil {
+		return nil, err
+	}
+	length, offsets, err := h.matcher.LongestPrefix(values)
+	if err != nil {
+		return nil, err
+	}
+	page, start, end, err := h.page(r, len(offsets))
+	if err != nil {
+		return nil, err
+	}
+	match := ""
+	if length > 0 {
+		match = h.text(offsets[0], length)
+	}
+	return PrefixResponse{pattern, length, match, page, h.occurrences(offsets[start:end])}, nil
+}
+
+type RepeatResponse struct {
+	Length int32  `json:"length"`
+	Count  int    `json:"count"`
+	Text   string `json:"text"`
+	Occurrences
+}
+
+type RepeatsResponse struct {
+	MinLength int32 `json:"minLength"`
+	Page
+	Repeats []RepeatResponse `json:"repeats"`
+}
+
+func (h *handler) repeatsPage(r *http.Request) (interface{}, error) {
+	minLength, err := intParameter(r, "min", defaultRepeatLength, 1, int(^uint32(0)>>1))
+	if err != nil {
+		return nil, err
+	}
+	repeats := h.repeatsOfLength(int32(minLength))
+	page, start, end, err := h.page(r, len(repeats))
+	if err != nil {
+		return nil, err
+	}
+	response := RepeatsResponse{int32(minLength), page, []RepeatResponse{}}
+	for _, repeat := range repeats[start:end] {
+		response.Repeats = append(response.Repeats, RepeatResponse{repeat.Length, len(repeat.Offsets),
+			h.text(repeat.Offsets[0], repeat.Length), h.occurrences(repeat.Offsets)})
+	}
+	return response, nil
+}
+
+// finding the repeats walks the whole tree, the result is kept for the next page
+func (h *handler) repeatsOfLength(minLength int32) []suffixtree.Repeat {
+	h.repeatsMutex.Lock()
+	defer h.repeatsMutex.Unlock()
+	if h.repeats == nil || h.repeatsLength != minLength {
+		h.repeats = suffixtree.Repeats(h.tree, minLength)
+		h.repeatsLength = minLength
+	}
+	return h.repeats
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jojohannsen/suffixtree"
+)
+
+func newTestServer(t *testing.T, dataSource suffixtree.DataSource, opts *Options) *httptest.Server {
+	t.Helper()
+	tree, err := suffixtree.Build(context.Background(), dataSource, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(NewHandler(tree, opts))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// get a path, decoding the JSON response into response
+func get(t *testing.T, server *httptest.Server, path string, response interface{}) int {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("%s: content type %q", path, resp.Header.Get("Content-Type"))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return resp.StatusCode
+}
+
+func TestFind(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource(strings.Repeat("abcab", 10)), &Options{DefaultLimit: 4})
+	var response FindResponse
+	if status := get(t, server, "/find?q=cab", &response); status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	want := FindResponse{"cab", Page{10, 0, 4}, Occurrences{Offsets: []int32{2, 7, 12, 17}}}
+	if !reflect.DeepEqual(response, want) {
+		t.Errorf("got %+v, want %+v", response, want)
+	}
+	response = FindResponse{}
+	get(t, server, "/find?q=cab&offset=8&limit=5", &response)
+	if want := []int32{42, 47}; response.Total != 10 || !reflect.DeepEqual(response.Offsets, want) {
+		t.Errorf("last page %+v, want offsets %v", response, want)
+	}
+}
+
+func TestCount(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource(strings.Repeat("abcab", 10)), nil)
+	for pattern, want := range map[string]int{"ab": 20, "abcab": 10, "x": 0} {
+		var response CountResponse
+		if status := get(t, server, "/count?q="+pattern, &response); status != http.StatusOK || response.Count != want {
+			t.Errorf("count of %q: status %d, %+v, want %d", pattern, status, response, want)
+		}
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource("abcabd"), nil)
+	var response PrefixResponse
+	get(t, server, "/prefix?q=abx", &response)
+	if response.Length != 2 || response.Match != "ab" || !reflect.DeepEqual(response.Offsets, []int32{0, 3}) {
+		t.Errorf("got %+v", response)
+	}
+	response = PrefixResponse{}
+	get(t, server, "/prefix?q=x", &response)
+	if response.Length != 0 || response.Match != "" || response.Total != 0 {
+		t.Errorf("got %+v for no match", response)
+	}
+}
+
+func TestRepeats(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource("xabcyabcz"), nil)
+	var response RepeatsResponse
+	get(t, server, "/repeats?min=2", &response)
+	if response.Total != 1 || len(response.Repeats) != 1 {
+		t.Fatalf("got %+v", response)
+	}
+	want := RepeatResponse{3, 2, "abc", Occurrences{Offsets: []int32{1, 5}}}
+	if !reflect.DeepEqual(response.Repeats[0], want) {
+		t.Errorf("got %+v, want %+v", response.Repeats[0], want)
+	}
+}
+
+func TestGeneralizedOffsets(t *testing.T) {
+	sequences := suffixtree.NewGeneralizedDataSource([]suffixtree.STKey{'a', 'b'}, []suffixtree.STKey{'b', 'a', 'b'})
+	server := newTestServer(t, sequences, nil)
+	var response FindResponse
+	get(t, server, "/find?q=ab", &response)
+	want := Occurrences{Offsets: []int32{0, 1}, Sequences: []int{0, 1}}
+	if !reflect.DeepEqual(response.Occurrences, want) {
+		t.Errorf("got %+v, want %+v", response.Occurrences, want)
+	}
+}
+
+func TestRunes(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource("café café"), &Options{Runes: true})
+	var response PrefixResponse
+	get(t, server, "/prefix?q=caf%C3%A9s", &response)
+	if response.Length != 4 || response.Match != "café" || !reflect.DeepEqual(response.Offsets, []int32{0, 5}) {
+		t.Errorf("got %+v", response)
+	}
+}
+
+func TestBadRequests(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource("abcab"), nil)
+	for path, want := range map[string]int{
+		"/find":               http.StatusBadRequest,
+		"/find?q=":            http.StatusBadRequest,
+		"/count?q=":           http.StatusBadRequest,
+		"/prefix?q=":          http.StatusBadRequest,
+		"/find?q=a&limit=0":   http.StatusBadRequest,
+		"/find?q=a&offset=-1": http.StatusBadRequest,
+		"/repeats?min=x":      http.StatusBadRequest,
+		"/missing":            http.StatusNotFound,
+	} {
+		var response errorResponse
+		if status := get(t, server, path, &response); status != want || response.Error == "" {
+			t.Errorf("%s: status %d, %+v, want status %d", path, status, response, want)
+		}
+	}
+	resp, err := http.Post(server.URL+"/find?q=a", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST: status %d", resp.StatusCode)
+	}
+}
+
+// a pattern running past the end of the data fails no later request
+func TestPatternPastTheEnd(t *testing.T) {
+	server := newTestServer(t, suffixtree.NewStringDataSource("call me ishmael. whale"), nil)
+	var found FindResponse
+	if status := get(t, server, "/find?q=whale%24zz", &found); status != http.StatusOK || found.Total != 0 {
+		t.Fatalf("status %d, %+v", status, found)
+	}
+	found = FindResponse{}
+	if status := get(t, server, "/find?q=ale", &found); status != http.StatusOK || found.Total != 1 {
+		t.Errorf("find after: status %d, %+v", status, found)
+	}
+	var count CountResponse
+	if status := get(t, server, "/count?q=a", &count); status != http.StatusOK || count.Count != 3 {
+		t.Errorf("count after: status %d, %+v", status, count)
+	}
+	var prefix PrefixResponse
+	if status := get(t, server, "/prefix?q=whale%24", &prefix); status != http.StatusOK || prefix.Length != 5 {
+		t.Errorf("prefix after: status %d, %+v", status, prefix)
+	}
+}
@@ -0,0 +1,231 @@
+package suffixtree
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// Builders other than Ukkonen work on the whole text in memory.  The text is followed by
+// the Terminator, as in a tree Ukkonen has finished, so trees from every builder are equivalent.
+
+// the values an offline builder works on
+type builderText interface {
+	at(offset int32) STKey
+	length() int32
+	slice(start, end int32) builderText
+}
+
+// a text whose values all fit in a byte, such as a file's, takes one byte per value
+type byteText []byte
+
+func (text byteText) at(offset int32) STKey              { return STKey(text[offset]) }
+func (text byteText) length() int32                      { return int32(len(text)) }
+func (text byteText) slice(start, end int32) builderText { return text[start:end] }
+
+type keyText []STKey
+
+func (text keyText) at(offset int32) STKey              { return text[offset] }
+func (text keyText) length() int32                      { return int32(len(text)) }
+func (text keyText) slice(start, end int32) builderText { return text[start:end] }
+
+// read the data source to the end, returning the values followed by the Terminator.  The values
+// are kept as bytes until one does not fit in a byte.
+func readTerminatedText(ctx context.Context, dataSource DataSource) (builderText, error) {
+	capacity := 0
+	if sized, ok := dataSource.(lengthKnown); ok {
+		capacity = int(sized.Len()) + 1
+	}
+	bytes := make([]byte, 0, capacity)
+	var keys []STKey
+	dataChannel := dataSource.STKeys()
+	for {
+		select {
+		case <-ctx.Done():
+			stopStream(dataSource)
+			return nil, ctx.Err()
+		case value, ok := <-dataChannel:
+			if !ok {
+				if err := sourceErr(dataSource); err != nil {
+					return nil, err
+				}
+				if keys != nil {
+					return keyText(append(keys, Terminator)), nil
+				}
+				return byteText(append(bytes, byte(Terminator))), nil
+			}
+			if keys == nil && value >= 0 && value <= math.MaxUint8 {
+				bytes = append(bytes, byte(value))
+				continue
+			}
+			if keys == nil {
+				keys = make([]STKey, len(bytes), capacity)
+				for i, b := range bytes {
+					keys[i] = STKey(b)
+				}
+				bytes = nil
+			}
+			keys = append(keys, value)
+		}
+	}
+}
+
+// the length of an edge's label, leaf edges run to the end of the text
+func labelLength(text builderText, edge *Edge) int32 {
+	if edge.EndOffset == FinalOffset {
+		return text.length() - edge.StartOffset
+	}
+	return edge.length()
+}
+
+// a leaf for a suffix whose offset is already known, avoiding the walk to the root in NewLeafEdgeNode
+func newLeafForSuffix(id int32, parent Node, edgeStart, suffixOffset int32) (*Edge, Node) {
+	leafEdge := NewLeafEdge(edgeStart)
+	return leafEdge, &leafNode{
+		hasId{id},
+		noOutgoing{},
+		hasIncomingEdge{parent, leafEdge},
+		hasSuffixLink{nil}, suffixOffset}
+}
+
+// split the edge above child after splitOffset values, returning the new internal node
+func splitEdge(factory *idFactory, text builderText, parent, child Node, splitOffset int32) Node {
+	topEdge := child.IncomingEdge()
+	bottomEdge := NewEdge(topEdge.StartOffset+splitOffset, topEdge.EndOffset)
+	topEdge.EndOffset = bottomEdge.StartOffset - 1
+	internalNode := NewInternalNode(factory.NextId(), parent, topEdge)
+	parent.AddOutgoingEdgeNode(text.at(topEdge.StartOffset), topEdge, internalNode)
+	internalNode.AddOutgoingEdgeNode(text.at(bottomEdge.StartOffset), bottomEdge, child)
+	child.setIncoming(internalNode, bottomEdge)
+	return internalNode
+}
+
+// build the tree of the given suffixes from their sorted order, lcps[i] being the length of the
+// prefix suffixes[i] shares with suffixes[i-1].  A suffix that is a prefix of the next one gets no
+// leaf, just as Ukkonen leaves it implicit when the data contains the Terminator.
+func buildFromSorted(factory *idFactory, text builderText, suffixes, lcps []int32) Node {
+	type stackEntry struct {
+		node  Node
+		depth int32
+	}
+	root := NewRootNode(factory.NextId())
+	n := text.length()
+	stack := []stackEntry{{root, 0}}
+	carried := int32(-1)
+	for i, suffix := range suffixes {
+		lcp := lcps[i]
+		if carried >= 0 && carried < lcp {
+			lcp = carried
+		}
+		if i+1 < len(suffixes) && lcps[i+1] == n-suffix {
+			carried = lcp
+			continue
+		}
+		carried = -1
+
+		var last Node
+		for stack[len(stack)-1].depth > lcp {
+			last = stack[len(stack)-1].node
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+		if top.depth < lcp {
+			internal := splitEdge(factory, text, top.node, last, lcp-top.depth)
+			top = stackEntry{internal, lcp}
+			stack = append(stack, top)
+		}
+		edge, leaf := newLeafForSuffix(factory.NextId(), top.node, suffix+lcp, suffix)
+		top.node.AddOutgoingEdgeNode(text.at(suffix+lcp), edge, leaf)
+		stack = append(stack, stackEntry{leaf, n - suffix})
+	}
+	return root
+}
+
+// attach child below parent, merging it with any existing path that starts with the same value.
+// Used to join subtrees built separately over disjoint sets of suffixes.
+func mergeSubtree(factory *idFactory, text builderText, parent Node, edge *Edge, child Node) {
+	key := text.at(edge.StartOffset)
+	existingEdge, existing := parent.outgoingEdgeNode(key)
+	if existing == nil {
+		parent.AddOutgoingEdgeNode(key, edge, child)
+		child.setIncoming(parent, edge)
+		return
+	}
+	newLength := labelLength(text, edge)
+	existingLength := labelLength(text, existingEdge)
+	common := int32(1)
+	for common < newLength && common < existingLength &&
+		text.at(edge.StartOffset+common) == text.at(existingEdge.StartOffset+common) {
+		common++
+	}
+
+	switch {
+	case common < newLength && common < existingLength:
+		internal := splitEdge(factory, text, parent, existing, common)
+		edge.StartOffset += common
+		mergeSubtree(factory, text, internal, edge, child)
+	case common < newLength:
+		if existing.IsLeaf() {
+			// the existing suffix is a prefix of the new path, so it is implicit
+			parent.removeEdgeFollowing(key)
+			mergeSubtree(factory, text, parent, edge, child)
+			return
+		}
+		edge.StartOffset += common
+		mergeSubtree(factory, text, existing, edge, child)
+	case common < existingLength:
+		if child.IsLeaf() {
+			return
+		}
+		internal := splitEdge(factory, text, parent, existing, common)
+		mergeChildren(factory, text, internal, child)
+	default:
+		switch {
+		case child.IsLeaf():
+		case existing.IsLeaf():
+			parent.removeEdgeFollowing(key)
+			mergeSubtree(factory, text, parent, edge, child)
+		default:
+			mergeChildren(factory, text, existing, child)
+		}
+	}
+}
+
+func mergeChildren(factory *idFactory, text builderText, parent, from Node) {
+	for key, child := range from.outgoingNodeMap() {
+		mergeSubtree(factory, text, parent, from.OutgoingEdgeMap()[key], child)
+	}
+}
+
+// set the suffix link of every internal node, parents before children so each link can be
+// found by walking down from the parent's link
+func setSuffixLinks(root Node, text builderText) error {
+	queue := root.OutgoingNodes()
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if node.IsLeaf() {
+			continue
+		}
+		edge := node.IncomingEdge()
+		start, length := edge.StartOffset, edge.length()
+		from := node.parent()
+		if from.isRoot() {
+			start, length = start+1, length-1
+		} else {
+			from = from.SuffixLink()
+		}
+		for length > 0 {
+			nextEdge, next := from.outgoingEdgeNode(text.at(start))
+			if next == nil || labelLength(text, nextEdge) > length {
+				return fmt.Errorf("%w: suffix link of node %d does not end on a node", ErrMalformedTree, node.Id())
+			}
+			from = next
+			start += labelLength(text, nextEdge)
+			length -= labelLength(text, nextEdge)
+		}
+		node.SetSuffixLink(from)
+		queue = append(queue, node.OutgoingNodes()...)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package suffixtree
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"unsafe"
+)
+
+// TreeStats describes the shape and size of a finished tree, see Stats
+type TreeStats struct {
+	// values in the data source, not counting the Terminator
+	Values int64
+	// Nodes counts the root, the internal nodes and the leaves
+	Nodes         int64
+	InternalNodes int64
+	Leaves        int64
+	// suffixes without a leaf of their own, because the data contains the Terminator
+	ImplicitSuffixes int64
+	// number of root and internal nodes with each number of children
+	Branching map[int]int64
+	// string depth (values from the root) of the internal nodes
+	MaxDepth  int32
+	MeanDepth float64
+	// EdgeLengths[i] counts the edges selecting from 2^i up to 2^(i+1)-1 values,
+	// leaf edges measured to the Terminator
+	EdgeLengths []int64
+	Memory      MemoryStats
+}
+
+// MemoryStats estimates the bytes used by each part of a tree.  Map sizes depend on the Go
+// runtime, so the figures are approximate.
+type MemoryStats struct {
+	Nodes     int64 // node structs
+	Edges     int64 // Edge structs
+	ChildMaps int64 // the two maps from values to children in the root and every internal node
+	Data      int64 // values held in memory by the data source, 0 for file data sources
+	Total     int64
+}
+
+var (
+	rootNodeSize     = int64(unsafe.Sizeof(rootNode{}))
+	internalNodeSize = int64(unsafe.Sizeof(internalNode{}))
+	leafNodeSize     = int64(unsafe.Sizeof(leafNode{}))
+	edgeSize         = int64(unsafe.Sizeof(Edge{}))
+	edgeEntrySize    = int64(unsafe.Sizeof(STKey(0)) + unsafe.Sizeof((*Edge)(nil)))
+	nodeEntrySize    = int64(unsafe.Sizeof(STKey(0)) + unsafe.Sizeof(Node(nil)))
+)
+
+// estimated size of a map: a header, then groups of 8 slots with a control word, at most 7/8 full
+func mapSize(entries int, entrySize int64) int64 {
+	const header, slots = 48, 8
+	groups := int64(entries*8/7+slots-1) / slots
+	if groups == 0 {
+		groups = 1
+	}
+	return header + groups*(8+slots*entrySize)
+}
+
+// Stats walks a finished tree and reports its size and shape
+func Stats(tree SuffixTree) TreeStats {
+	stats := TreeStats{Branching: make(map[int]int64)}
+	dataSource := tree.DataSource()
+	memory := &stats.Memory
+	switch source := dataSource.(type) {
+	case *stringDataSource:
+		memory.Data = int64(len(source.runes)) * int64(unsafe.Sizeof(rune(0)))
+	case *GeneralizedDataSource:
+		memory.Data = int64(len(source.runes))*int64(unsafe.Sizeof(rune(0))) + int64(len(source.starts))*4
+	}
+
+	type entry struct {
+		node  Node
+		depth int32
+	}
+	edgeLengths := []int32{}
+	// leaf edges run to the Terminator, their lengths are known once the number of values is
+	leafStarts := []int32{}
+	depthTotal := int64(0)
+	stack := []entry{{tree.Root(), 0}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := top.node
+		stats.Nodes++
+		switch {
+		case node.isRoot():
+			memory.Nodes += rootNodeSize
+		default:
+			stats.InternalNodes++
+			memory.Nodes += internalNodeSize
+			depthTotal += int64(top.depth)
+			if top.depth > stats.MaxDepth {
+				stats.MaxDepth = top.depth
+			}
+		}
+		stats.Branching[node.NumberOutgoing()]++
+		memory.ChildMaps += mapSize(node.NumberOutgoing(), edgeEntrySize) + mapSize(node.NumberOutgoing(), nodeEntrySize)
+		for _, child := range node.OutgoingNodes() {
+			memory.Edges += edgeSize
+			edge := child.IncomingEdge()
+			if child.IsLeaf() {
+				stats.Nodes++
+				stats.Leaves++
+				memory.Nodes += leafNodeSize
+				leafStarts = append(leafStarts, edge.StartOffset)
+				continue
+			}
+			length := edge.length()
+			edgeLengths = append(edgeLengths, length)
+			stack = append(stack, entry{child, top.depth + length})
+		}
+	}
+
+	if sized, ok := dataSource.(lengthKnown); ok {
+		stats.Values = sized.Len()
+	} else {
+		stats.Values = stats.Leaves - 1
+	}
+	stats.ImplicitSuffixes = stats.Values + 1 - stats.Leaves
+	if stats.InternalNodes > 0 {
+		stats.MeanDepth = float64(depthTotal) / float64(stats.InternalNodes)
+	}
+	for _, start := range leafStarts {
+		edgeLengths = append(edgeLengths, int32(stats.Values)+1-start)
+	}
+	for _, length := range edgeLengths {
+		if length < 1 {
+			length = 1
+		}
+		bucket := bits.Len32(uint32(length)) - 1
+		for len(stats.EdgeLengths) <= bucket {
+			stats.EdgeLengths = append(stats.EdgeLengths, 0)
+		}
+		stats.EdgeLengths[bucket]++
+	}
+	memory.Total = memory.Nodes + memory.Edges + memory.ChildMaps + memory.Data
+	return stats
+}
+
+// Write prints the statistics, one per line
+func (stats TreeStats) Write(w io.Writer) error {
+	lines := []string{
+		fmt.Sprintf("values\t%d", stats.Values),
+		fmt.Sprintf("nodes\t%d", stats.Nodes),
+		fmt.Sprintf("internal nodes\t%d", stats.InternalNodes),
+		fmt.Sprintf("leaves\t%d", stats.Leaves),
+		fmt.Sprintf("implicit suffixes\t%d", stats.ImplicitSuffixes),
+		fmt.Sprintf("max depth\t%d", stats.MaxDepth),
+		fmt.Sprintf("mean depth\t%.2f", stats.MeanDepth),
+	}
+	children := make([]int, 0, len(stats.Branching))
+	for n := range stats.Branching {
+		children = append(children, n)
+	}
+	sort.Ints(children)
+	for _, n := range children {
+		lines = append(lines, fmt.Sprintf("nodes with %d children\t%d", n, stats.Branching[n]))
+	}
+	for i, count := range stats.EdgeLengths {
+		lines = append(lines, fmt.Sprintf("edges of length %d-%d\t%d", 1<<uint(i), 1<<uint(i+1)-1, count))
+	}
+	memory := stats.Memory
+	lines = append(lines,
+		fmt.Sprintf("memory: nodes\t%d", memory.Nodes),
+		fmt.Sprintf("memory: edges\t%d", memory.Edges),
+		fmt.Sprintf("memory: child maps\t%d", memory.ChildMaps),
+		fmt.Sprintf("memory: data\t%d", memory.Data),
+		fmt.Sprintf("memory: total\t%d", memory.Total))
+	for _, line := range lines {
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
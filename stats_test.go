@@ -0,0 +1,78 @@
+package suffixtree
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// the nonempty substrings of t followed by at least two different values, which are the paths
+// of the internal nodes
+func branchingSubstrings(t string) map[string]int {
+	values := []rune(t)
+	following := map[string]map[rune]bool{}
+	for i := range values {
+		for j := i + 1; j < len(values); j++ {
+			w := string(values[i:j])
+			if following[w] == nil {
+				following[w] = map[rune]bool{}
+			}
+			following[w][values[j]] = true
+		}
+	}
+	result := map[string]int{}
+	for w, next := range following {
+		if len(next) > 1 {
+			result[w] = len(next)
+		}
+	}
+	return result
+}
+
+func TestStats(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		s := randomString(r, r.Intn(40), []string{"a", "ab", "abc", "ab$"}[i%4])
+		stats := Stats(buildString(t, s))
+		terminated := s + "$"
+		branching := branchingSubstrings(terminated)
+		values, leaves := []rune(terminated), 0
+		for suffix := range values {
+			// a suffix ending inside the tree is the start of an earlier one
+			if strings.Index(terminated, string(values[suffix:])) == len(string(values[:suffix])) {
+				leaves++
+			}
+		}
+		maxDepth, totalDepth := 0, 0
+		for w := range branching {
+			totalDepth += len([]rune(w))
+			if len([]rune(w)) > maxDepth {
+				maxDepth = len([]rune(w))
+			}
+		}
+		n := len([]rune(s))
+		if stats.Values != int64(n) || stats.InternalNodes != int64(len(branching)) || stats.Leaves != int64(leaves) ||
+			stats.Nodes != stats.InternalNodes+stats.Leaves+1 || stats.ImplicitSuffixes != int64(n+1-leaves) ||
+			stats.MaxDepth != int32(maxDepth) {
+			t.Fatalf("%q: %+v, want %d internal nodes, %d leaves, max depth %d", s, stats, len(branching), leaves, maxDepth)
+		}
+		if len(branching) > 0 && stats.MeanDepth != float64(totalDepth)/float64(len(branching)) {
+			t.Errorf("%q: mean depth %f", s, stats.MeanDepth)
+		}
+		withChildren, edges := int64(0), int64(0)
+		for _, count := range stats.Branching {
+			withChildren += count
+		}
+		for _, count := range stats.EdgeLengths {
+			edges += count
+		}
+		if withChildren != stats.InternalNodes+1 || edges != stats.Nodes-1 {
+			t.Errorf("%q: %d nodes with children, %d edges, %+v", s, withChildren, edges, stats)
+		}
+		var out bytes.Buffer
+		if err := stats.Write(&out); err != nil || !strings.HasPrefix(out.String(), "values\t") {
+			t.Errorf("%q: Write gave %q, %v", s, out.String(), err)
+		}
+	}
+}
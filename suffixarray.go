@@ -0,0 +1,207 @@
+package suffixtree
+
+import "sort"
+
+// Build the tree from the suffix array (SA-IS) and the LCP array (Kasai et al.), both linear time.
+func buildFromSuffixArray(factory *idFactory, text builderText) (Node, error) {
+	suffixes := suffixArray(text)
+	root := buildFromSorted(factory, text, suffixes, lcpArray(text, suffixes))
+	return root, setSuffixLinks(root, text)
+}
+
+// suffixArray returns the offsets of the suffixes of text in sorted order,
+// a suffix that is a prefix of another sorts first
+func suffixArray(text builderText) []int32 {
+	// replace the values by their rank, 1 for the smallest, and add a 0 sentinel for SA-IS
+	n := text.length()
+	ranked := make([]int32, n+1)
+	alphabetSize := int32(1)
+	if bytes, ok := text.(byteText); ok {
+		var ranks [256]int32
+		for _, b := range bytes {
+			ranks[b] = 1
+		}
+		for i := range ranks {
+			if ranks[i] > 0 {
+				ranks[i] = alphabetSize
+				alphabetSize++
+			}
+		}
+		for i, b := range bytes {
+			ranked[i] = ranks[b]
+		}
+		return sais(ranked, alphabetSize)[1:]
+	}
+	ranks := make(map[STKey]int32)
+	for offset := int32(0); offset < n; offset++ {
+		ranks[text.at(offset)] = 0
+	}
+	distinct := make([]STKey, 0, len(ranks))
+	for value := range ranks {
+		distinct = append(distinct, value)
+	}
+	sort.Slice(distinct, func(i, j int) bool { return distinct[i] < distinct[j] })
+	for _, value := range distinct {
+		ranks[value] = alphabetSize
+		alphabetSize++
+	}
+	for offset := int32(0); offset < n; offset++ {
+		ranked[offset] = ranks[text.at(offset)]
+	}
+	return sais(ranked, alphabetSize)[1:]
+}
+
+// SA-IS (Nong, Zhang and Chan), s must end with a unique 0 and its values are below alphabetSize
+func sais(s []int32, alphabetSize int32) []int32 {
+	n := len(s)
+	sa := make([]int32, n)
+	if n == 1 {
+		return sa
+	}
+
+	// S-type suffixes sort before the following suffix, L-type after
+	sType := make([]bool, n)
+	sType[n-1] = true
+	for i := n - 2; i >= 0; i-- {
+		sType[i] = s[i] < s[i+1] || (s[i] == s[i+1] && sType[i+1])
+	}
+	isLMS := func(i int32) bool {
+		return i > 0 && sType[i] && !sType[i-1]
+	}
+
+	bucket := make([]int32, alphabetSize)
+	bucketBounds := func(ends bool) {
+		for i := range bucket {
+			bucket[i] = 0
+		}
+		for _, value := range s {
+			bucket[value]++
+		}
+		sum := int32(0)
+		for i, count := range bucket {
+			sum += count
+			if ends {
+				bucket[i] = sum
+			} else {
+				bucket[i] = sum - count
+			}
+		}
+	}
+	induce := func() {
+		bucketBounds(false)
+		for i := 0; i < n; i++ {
+			if j := sa[i] - 1; sa[i] > 0 && !sType[j] {
+				sa[bucket[s[j]]] = j
+				bucket[s[j]]++
+			}
+		}
+		bucketBounds(true)
+		for i := n - 1; i >= 0; i-- {
+			if j := sa[i] - 1; sa[i] > 0 && sType[j] {
+				bucket[s[j]]--
+				sa[bucket[s[j]]] = j
+			}
+		}
+	}
+	placeLMS := func(lms []int32) {
+		for i := range sa {
+			sa[i] = -1
+		}
+		bucketBounds(true)
+		for i := len(lms) - 1; i >= 0; i-- {
+			bucket[s[lms[i]]]--
+			sa[bucket[s[lms[i]]]] = lms[i]
+		}
+	}
+
+	// sort the LMS substrings
+	lms := []int32{}
+	for i := int32(1); i < int32(n); i++ {
+		if isLMS(i) {
+			lms = append(lms, i)
+		}
+	}
+	placeLMS(lms)
+	induce()
+
+	// name each LMS substring by its rank, equal substrings sharing a name
+	sortedLMS := make([]int32, 0, len(lms))
+	for _, suffix := range sa {
+		if isLMS(suffix) {
+			sortedLMS = append(sortedLMS, suffix)
+		}
+	}
+	names := make([]int32, n)
+	for i := range names {
+		names[i] = -1
+	}
+	name, previous := int32(-1), int32(-1)
+	for _, suffix := range sortedLMS {
+		if previous < 0 || !equalLMSSubstrings(s, sType, isLMS, previous, suffix) {
+			name++
+		}
+		names[suffix] = name
+		previous = suffix
+	}
+
+	// sort the LMS suffixes, recursing if any LMS substrings are equal
+	reduced := make([]int32, 0, len(lms))
+	for _, suffix := range lms {
+		reduced = append(reduced, names[suffix])
+	}
+	var reducedSA []int32
+	if int(name)+1 < len(lms) {
+		reducedSA = sais(reduced, name+1)
+	} else {
+		reducedSA = make([]int32, len(lms))
+		for i, rank := range reduced {
+			reducedSA[rank] = int32(i)
+		}
+	}
+	for i, rank := range reducedSA {
+		sortedLMS[i] = lms[rank]
+	}
+	placeLMS(sortedLMS)
+	induce()
+	return sa
+}
+
+func equalLMSSubstrings(s []int32, sType []bool, isLMS func(int32) bool, a, b int32) bool {
+	n := int32(len(s))
+	for d := int32(0); a+d < n && b+d < n; d++ {
+		if s[a+d] != s[b+d] || sType[a+d] != sType[b+d] {
+			return false
+		}
+		if d > 0 && (isLMS(a+d) || isLMS(b+d)) {
+			return isLMS(a+d) && isLMS(b+d)
+		}
+	}
+	return false
+}
+
+// lcpArray returns, for each suffix in sorted order, the length of the prefix it shares with
+// the suffix before it (Kasai et al.)
+func lcpArray(text builderText, suffixes []int32) []int32 {
+	n := int32(len(suffixes))
+	rank := make([]int32, n)
+	for i, suffix := range suffixes {
+		rank[suffix] = int32(i)
+	}
+	lcps := make([]int32, n)
+	common := int32(0)
+	for suffix := int32(0); suffix < n; suffix++ {
+		if rank[suffix] == 0 {
+			common = 0
+			continue
+		}
+		previous := suffixes[rank[suffix]-1]
+		for suffix+common < n && previous+common < n && text.at(suffix+common) == text.at(previous+common) {
+			common++
+		}
+		lcps[rank[suffix]] = common
+		if common > 0 {
+			common--
+		}
+	}
+	return lcps
+}
@@ -0,0 +1,85 @@
+package suffixtree
+
+// SuffixLinkIterator follows suffix links from a node to the root
+type SuffixLinkIterator struct {
+	next    Node
+	current Node
+}
+
+// SuffixLinkWalk iterates over node, the node for its next-shorter suffix, and so on, ending at
+// the root.  In a tree that is not finished, leaves have no suffix links and the walk ends early.
+//
+//	for it := SuffixLinkWalk(node); it.Next(); {
+//		visit(it.Node())
+//	}
+func SuffixLinkWalk(node Node) *SuffixLinkIterator {
+	return &SuffixLinkIterator{next: node}
+}
+
+// Next moves to the next node of the walk, returning false once the walk is over
+func (it *SuffixLinkIterator) Next() bool {
+	if it.next == nil {
+		return false
+	}
+	it.current = it.next
+	if it.current.isRoot() {
+		it.next = nil
+	} else {
+		it.next = it.current.SuffixLink()
+	}
+	return true
+}
+
+func (it *SuffixLinkIterator) Node() Node {
+	return it.current
+}
+
+// link each leaf to the leaf of the next suffix, the last leaf (the Terminator on its own) to the root.
+// When the data contains the Terminator the last suffixes end inside the tree instead of at a leaf,
+// the leaf before them links to the deepest node on the path of the next suffix.  length is the
+// offset of the Terminator.
+func linkLeaves(root Node, dataSource DataSource, length int32) {
+	leaves := []Node{}
+	for nodes := []Node{root}; len(nodes) > 0; {
+		node := nodes[len(nodes)-1]
+		nodes = nodes[:len(nodes)-1]
+		if !node.IsLeaf() {
+			nodes = append(nodes, node.OutgoingNodes()...)
+			continue
+		}
+		for int(node.SuffixOffset()) >= len(leaves) {
+			leaves = append(leaves, nil)
+		}
+		leaves[node.SuffixOffset()] = node
+	}
+	for suffix, leaf := range leaves {
+		next := suffix + 1
+		switch {
+		case leaf == nil:
+		case next < len(leaves) && leaves[next] != nil:
+			leaf.SetSuffixLink(leaves[next])
+		default:
+			leaf.SetSuffixLink(deepestNodeOn(root, dataSource, int32(next), length))
+		}
+	}
+}
+
+// the deepest node whose path starts the suffix at offset start, which must be in the tree.  The
+// suffix ends with the Terminator at offset length, an offset past it is the empty suffix.
+func deepestNodeOn(root Node, dataSource DataSource, start, length int32) Node {
+	node := root
+	for offset := start; offset <= length; {
+		value := Terminator
+		if offset < length {
+			value = dataSource.KeyAtOffset(offset)
+		}
+		edge, child := node.outgoingEdgeNode(value)
+		if child == nil || child.IsLeaf() || offset+edge.length() > length+1 {
+			break
+		}
+		// the suffix is in the tree, so it follows the whole edge
+		offset += edge.length()
+		node = child
+	}
+	return node
+}
@@ -0,0 +1,82 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// the path of a node in the finished tree of s, a leaf's ends with the Terminator
+func nodePath(node Node, s string) string {
+	if node.IsLeaf() {
+		return string([]rune(s)[node.SuffixOffset():]) + string(rune(Terminator))
+	}
+	return keysString(pathTo(node, NewStringDataSource(s)))
+}
+
+// after Finish every node links to the deepest node whose path starts its path without the first
+// value: the node for exactly that path if there is one, as there is for every internal node
+func TestSuffixLinksAfterFinish(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	alphabets := []string{"a", "ab", "abc", "ab$", "a$"}
+	for i := 0; i < 500; i++ {
+		s := randomString(r, r.Intn(40), alphabets[i%len(alphabets)])
+		if i%7 == 0 {
+			s += "$"
+		}
+		for algorithm := Algorithm(0); algorithm <= ParallelAlgorithm; algorithm++ {
+			tree := buildWith(t, s, algorithm)
+			paths := map[string]Node{}
+			for nodes := []Node{tree.Root()}; len(nodes) > 0; {
+				node := nodes[len(nodes)-1]
+				nodes = nodes[:len(nodes)-1]
+				paths[nodePath(node, s)] = node
+				nodes = append(nodes, node.OutgoingNodes()...)
+			}
+			for path, node := range paths {
+				want := tree.Root()
+				if path != "" {
+					// the longest prefix of the next suffix that is a node's path
+					next := []rune(path)[1:]
+					for length := len(next); length >= 0; length-- {
+						if prefixNode, ok := paths[string(next[:length])]; ok {
+							want = prefixNode
+							break
+						}
+					}
+					if node.isInternal() && want != paths[string(next)] {
+						t.Fatalf("%q %s: no node for %q, the suffix of internal node %q", s, algorithm, string(next), path)
+					}
+				}
+				if link := node.SuffixLink(); link != want {
+					t.Fatalf("%q %s: node %d for %q links to %v, want node %d", s, algorithm, node.Id(), path, link, want.Id())
+				}
+			}
+			for _, node := range paths {
+				depth := len([]rune(nodePath(node, s))) + 1
+				for it := SuffixLinkWalk(node); it.Next(); {
+					next := len([]rune(nodePath(it.Node(), s)))
+					if next >= depth && !it.Node().isRoot() {
+						t.Fatalf("%q %s: walk from node %d is not getting shorter", s, algorithm, node.Id())
+					}
+					depth = next
+				}
+				if depth != 0 {
+					t.Fatalf("%q %s: walk from node %d ends at depth %d", s, algorithm, node.Id(), depth)
+				}
+			}
+		}
+	}
+}
+
+// leaves are collected without recursion, so a tree as deep as its data is long links them
+func TestLinkLeavesDeepTree(t *testing.T) {
+	tree := buildString(t, strings.Repeat("a", 200000))
+	leaf := tree.Root()
+	for !leaf.IsLeaf() {
+		_, leaf = leaf.outgoingEdgeNode('a')
+	}
+	if leaf.SuffixOffset() != 0 || leaf.SuffixLink() == nil || leaf.SuffixLink().SuffixOffset() != 1 {
+		t.Errorf("leaf for suffix %d links to %v", leaf.SuffixOffset(), leaf.SuffixLink())
+	}
+}
@@ -0,0 +1,214 @@
+package suffixtree
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// A Tracer receives an Event for each step Ukkonen's algorithm takes, see Ukkonen.Trace.
+// Trace is called from the building goroutine, the tree must not be changed from it.
+type Tracer interface {
+	Trace(event Event)
+}
+
+// TracerFunc lets a function be used as a Tracer
+type TracerFunc func(event Event)
+
+func (f TracerFunc) Trace(event Event) {
+	f(event)
+}
+
+// An Event is one of ExtendStarted, LeafCreated, EdgeSplit, SuffixLinkSet or LocationMoved.
+// Offset is always the offset of the value being added.
+type Event interface {
+	// Name is the event's type, such as "LeafCreated"
+	Name() string
+	// Attrs are the event's fields, for structured logging
+	Attrs() []slog.Attr
+}
+
+// ExtensionRule is the rule of Ukkonen's algorithm that added a leaf.  Rule 3, where the value
+// already follows the location and the phase ends, is a LocationMoved with MoveValuePresent.
+type ExtensionRule int
+
+const (
+	// rule 2, the location is on a node without a child for the value
+	ExtensionNewLeaf ExtensionRule = iota
+	// rule 2, the location is inside an edge, which is split to hold the new leaf
+	ExtensionSplitEdge
+)
+
+func (rule ExtensionRule) String() string {
+	switch rule {
+	case ExtensionNewLeaf:
+		return "new-leaf"
+	case ExtensionSplitEdge:
+		return "split-edge"
+	}
+	return fmt.Sprintf("ExtensionRule(%d)", int(rule))
+}
+
+// MoveCause is why the location moved
+type MoveCause int
+
+const (
+	// rule 3, the value already follows the location, which moves past it
+	MoveValuePresent MoveCause = iota
+	// the location is on the node just created by splitting its edge
+	MoveSplitNode
+	// the location moved to the next shorter suffix, through a suffix link or from the root
+	MoveNextSuffix
+)
+
+func (cause MoveCause) String() string {
+	switch cause {
+	case MoveValuePresent:
+		return "value-present"
+	case MoveSplitNode:
+		return "split-node"
+	case MoveNextSuffix:
+		return "next-suffix"
+	}
+	return fmt.Sprintf("MoveCause(%d)", int(cause))
+}
+
+// LocationState is a copy of a Location, taken when an event happened
+type LocationState struct {
+	Node          int32 // the node the location is on, or the node below its edge
+	OnNode        bool
+	Edge          Edge // the edge the location is on, the zero Edge when on a node
+	OffsetFromTop int32
+}
+
+func locationState(location *Location) LocationState {
+	state := LocationState{Node: location.Base.Id(), OnNode: location.OnNode}
+	if !location.OnNode && location.Edge != nil {
+		state.Edge = *location.Edge
+		state.OffsetFromTop = location.OffsetFromTop
+	}
+	return state
+}
+
+func (state LocationState) String() string {
+	if state.OnNode {
+		return fmt.Sprintf("on node %d", state.Node)
+	}
+	return fmt.Sprintf("%d into edge %s above node %d", state.OffsetFromTop, state.Edge.String(), state.Node)
+}
+
+func (state LocationState) attr() slog.Attr {
+	if state.OnNode {
+		return slog.Group("location", slog.Int("node", int(state.Node)))
+	}
+	return slog.Group("location", slog.Int("node", int(state.Node)),
+		slog.String("edge", state.Edge.String()), slog.Int("offsetFromTop", int(state.OffsetFromTop)))
+}
+
+// ExtendStarted begins the phase adding the value at Offset
+type ExtendStarted struct {
+	Offset   int32
+	Value    STKey
+	Location LocationState
+}
+
+func (e ExtendStarted) Name() string { return "ExtendStarted" }
+
+func (e ExtendStarted) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("offset", int(e.Offset)), slog.Int("value", int(e.Value)), e.Location.attr()}
+}
+
+// LeafCreated is a new leaf for the suffix starting at Suffix
+type LeafCreated struct {
+	Offset int32
+	Rule   ExtensionRule
+	Leaf   int32 // node ids
+	Parent int32
+	Suffix int32
+	Edge   Edge
+}
+
+func (e LeafCreated) Name() string { return "LeafCreated" }
+
+func (e LeafCreated) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("offset", int(e.Offset)), slog.String("rule", e.Rule.String()),
+		slog.Int("leaf", int(e.Leaf)), slog.Int("parent", int(e.Parent)), slog.Int("suffix", int(e.Suffix)),
+		slog.String("edge", e.Edge.String())}
+}
+
+// EdgeSplit is a new internal node Node, between Parent and Child
+type EdgeSplit struct {
+	Offset int32
+	Node   int32 // node ids
+	Parent int32
+	Child  int32
+	Top    Edge // the edge from Parent to Node
+	Bottom Edge // the edge from Node to Child
+}
+
+func (e EdgeSplit) Name() string { return "EdgeSplit" }
+
+func (e EdgeSplit) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("offset", int(e.Offset)), slog.Int("node", int(e.Node)),
+		slog.Int("parent", int(e.Parent)), slog.Int("child", int(e.Child)),
+		slog.String("top", e.Top.String()), slog.String("bottom", e.Bottom.String())}
+}
+
+// SuffixLinkSet is a suffix link from one internal node to another (or the root)
+type SuffixLinkSet struct {
+	Offset int32
+	From   int32 // node ids
+	To     int32
+}
+
+func (e SuffixLinkSet) Name() string { return "SuffixLinkSet" }
+
+func (e SuffixLinkSet) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("offset", int(e.Offset)), slog.Int("from", int(e.From)), slog.Int("to", int(e.To))}
+}
+
+// LocationMoved is the location after a move
+type LocationMoved struct {
+	Offset   int32
+	Cause    MoveCause
+	Location LocationState
+}
+
+func (e LocationMoved) Name() string { return "LocationMoved" }
+
+func (e LocationMoved) Attrs() []slog.Attr {
+	return []slog.Attr{slog.Int("offset", int(e.Offset)), slog.String("cause", e.Cause.String()), e.Location.attr()}
+}
+
+// EventString formats an event on one line, as "Name key=value ..."
+func EventString(event Event) string {
+	var builder strings.Builder
+	builder.WriteString(event.Name())
+	var write func(prefix string, attrs []slog.Attr)
+	write = func(prefix string, attrs []slog.Attr) {
+		for _, attr := range attrs {
+			if attr.Value.Kind() == slog.KindGroup {
+				write(prefix+attr.Key+".", attr.Value.Group())
+				continue
+			}
+			fmt.Fprintf(&builder, " %s%s=%v", prefix, attr.Key, attr.Value)
+		}
+	}
+	write("", event.Attrs())
+	return builder.String()
+}
+
+// NewSlogTracer logs every event to logger at the given level, the event's name as the message
+func NewSlogTracer(logger *slog.Logger, level slog.Level) Tracer {
+	return TracerFunc(func(event Event) {
+		logger.LogAttrs(context.Background(), level, event.Name(), event.Attrs()...)
+	})
+}
+
+// the Debug channel receives each event as a line of text
+type channelTracer chan string
+
+func (c channelTracer) Trace(event Event) {
+	c <- EventString(event)
+}
@@ -0,0 +1,111 @@
+package suffixtree
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// the events of building s with Ukkonen's algorithm
+func traceBuild(t *testing.T, s string) (SuffixTree, []Event) {
+	t.Helper()
+	events := []Event{}
+	u := NewUkkonen(NewStringDataSource(s))
+	u.Trace(TracerFunc(func(event Event) {
+		events = append(events, event)
+	}))
+	for u.Extend() {
+	}
+	if err := u.Finish(); err != nil {
+		t.Fatal(err)
+	}
+	return u.Tree(), events
+}
+
+// the events account for every value, leaf, internal node and suffix link of the tree
+func TestTraceEvents(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		s := randomString(r, r.Intn(40), []string{"a", "ab", "abc"}[i%3])
+		tree, events := traceBuild(t, s)
+		nodes := map[int32]Node{}
+		for stack := []Node{tree.Root()}; len(stack) > 0; {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			nodes[node.Id()] = node
+			stack = append(stack, node.OutgoingNodes()...)
+		}
+		values := stringKeys(s + "$")
+		started, leaves, splits := 0, map[int32]bool{}, 0
+		for _, event := range events {
+			switch e := event.(type) {
+			case ExtendStarted:
+				if e.Offset != int32(started) || e.Value != values[started] {
+					t.Fatalf("%q: phase %d started %+v", s, started, e)
+				}
+				started++
+			case LeafCreated:
+				leaf := nodes[e.Leaf]
+				if leaves[e.Suffix] || leaf == nil || !leaf.IsLeaf() || leaf.SuffixOffset() != e.Suffix {
+					t.Fatalf("%q: %s does not match the tree", s, EventString(e))
+				}
+				leaves[e.Suffix] = true
+			case EdgeSplit:
+				if node := nodes[e.Node]; node == nil || !node.isInternal() {
+					t.Fatalf("%q: %s created no internal node", s, EventString(e))
+				}
+				splits++
+			case SuffixLinkSet:
+				if from := nodes[e.From]; from == nil || from.SuffixLink() == nil || from.SuffixLink().Id() != e.To {
+					t.Fatalf("%q: %s does not match the tree", s, EventString(e))
+				}
+			}
+		}
+		internal := 0
+		for _, node := range nodes {
+			if node.isInternal() {
+				internal++
+			}
+		}
+		if started != len(values) || len(leaves) != len(values) || splits != internal {
+			t.Errorf("%q: %d phases, %d leaves, %d splits for %d internal nodes", s, started, len(leaves), splits, internal)
+		}
+	}
+}
+
+// the slog tracer and the Debug channel each get a line per event
+func TestTraceOutputs(t *testing.T) {
+	_, events := traceBuild(t, "abcabxabcd")
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, nil))
+	if _, err := Build(context.Background(), NewStringDataSource("abcabxabcd"),
+		&BuildOptions{Tracer: NewSlogTracer(logger, slog.LevelInfo)}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(logged.String(), "\n"), "\n")
+	if len(lines) != len(events) || !strings.Contains(lines[0], "msg=ExtendStarted offset=0") {
+		t.Errorf("logged %d lines for %d events, starting %q", len(lines), len(events), lines[0])
+	}
+
+	u := NewUkkonen(NewStringDataSource("abcabxabcd"))
+	debug := make(chan string)
+	u.Debug(debug)
+	go func() {
+		u.DrainDataSourceContext(context.Background())
+		u.Finish()
+		close(debug)
+	}()
+	i := 0
+	for line := range debug {
+		if i < len(events) && line != EventString(events[i]) {
+			t.Errorf("line %d is %q, want %q", i, line, EventString(events[i]))
+		}
+		i++
+	}
+	if i != len(events) {
+		t.Errorf("%d debug lines for %d events", i, len(events))
+	}
+}
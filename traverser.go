@@ -1,60 +1,78 @@
 package suffixtree
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type Traverser interface {
-	traverseToNextSuffix(location *Location, debugChannel chan string)
+	traverseToNextSuffix(location *Location)
 	traverseOne(location *Location, value STKey)
-	traverseDownValue(location *Location, value STKey) bool
+	traverseDownValue(location *Location, value STKey) (bool, error)
 }
 
 type traverser struct {
 	dataSource            DataSource
+	end                   int64 // the offset of the Terminator, past which no value is found
 	numberValuesTraversed int32
 	traversedDataOffset   int32
 }
 
 func NewTraverser(dataSource DataSource) Traverser {
-	return &traverser{dataSource, 0, 0}
+	end := int64(math.MaxInt32)
+	if sized, ok := dataSource.(lengthKnown); ok {
+		end = sized.Len()
+	}
+	return &traverser{dataSource, end, 0, 0}
 }
 
 func (t *traverser) String() string {
 	return fmt.Sprintf("(%d values starting at %d)", t.numberValuesTraversed, t.traversedDataOffset)
 }
 
-// traverse down a value, return true and location updated if value is present
-func (t *traverser) traverseDownValue(location *Location, value STKey) bool {
+// traverse down a value, return true and location updated if value is present.  The Terminator
+// at the end of the data is not a value, a '$' in the data is.
+func (t *traverser) traverseDownValue(location *Location, value STKey) (bool, error) {
 	if location.OnNode {
-		if location.Base.NodeFollowing(value) != nil {
-			t.traverseOne(location, value)
-			return true
-		} else {
-			return false
+		edge, node := location.Base.outgoingEdgeNode(value)
+		if edge == nil && node == nil {
+			return false, nil
+		}
+		if edge == nil || node == nil {
+			return false, fmt.Errorf("%w: node %d has an edge without a node for value %d",
+				ErrMalformedTree, location.Base.Id(), value)
 		}
+		if int64(edge.StartOffset) >= t.end {
+			return false, nil
+		}
+		t.traverseOne(location, value)
+		return true, nil
 	} else {
+		if location.Base.IncomingEdge() == nil {
+			return false, fmt.Errorf("%w: location is on an edge above node %d, which has no incoming edge",
+				ErrMalformedTree, location.Base.Id())
+		}
 		offsetToCheck := location.Base.IncomingEdge().StartOffset + location.OffsetFromTop
-		if t.dataSource.KeyAtOffset(offsetToCheck) == value {
+		if int64(offsetToCheck) >= t.end {
+			return false, nil
+		}
+		key, err := keyAt(t.dataSource, offsetToCheck)
+		if err != nil {
+			return false, err
+		}
+		if key == value {
 			t.traverseEdgeValue(location)
-			return true
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
 }
 
 // set the Location to be at the next suffix
-func (t *traverser) traverseToNextSuffix(location *Location, debugChannel chan string) {
+func (t *traverser) traverseToNextSuffix(location *Location) {
 	t.traverseUp(location)
-	if debugChannel != nil {
-		debugChannel <- fmt.Sprintf("   after traverseUp %s, numberValuesTraversed %d", location, t.numberValuesTraversed)
-	}
 	t.traverseSuffixLink(location)
-	if debugChannel != nil {
-		debugChannel <- fmt.Sprintf("   after traverseSuffixLinke %s", location)
-	}
 	t.traverseDown(location)
-	if debugChannel != nil {
-		debugChannel <- fmt.Sprintf("   after traverseDown %s", location)
-	}
 }
 
 func (t *traverser) traverseUp(location *Location) {
@@ -60,6 +60,8 @@ type stkarr []STKey
 func (a stkarr) Len() int           { return len(a) }
 func (a stkarr) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a stkarr) Less(i, j int) bool { return a[i] < a[j] }
+
+// TreeCheck panics on the first inconsistency it finds, Validate reports all of them
 func TreeCheck(node Node, dataSource DataSource) {
 	edgeChildren := stkarr{}
 	nodeChildren := stkarr{}
@@ -1,15 +1,18 @@
 package suffixtree
 
 import (
-	"fmt"
+	"context"
 	"sync"
+	"sync/atomic"
 )
 
 type Ukkonen interface {
 	Extend() bool
-	Finish()
+	Finish() error
 	Debug(dChan chan string)
+	Trace(tracer Tracer)
 	DrainDataSource()
+	DrainDataSourceContext(ctx context.Context) error
 	DrainDataSourceWithTicks(wg *sync.WaitGroup, tickChannel chan struct{})
 	Tree() SuffixTree
 	Location() *Location
@@ -28,15 +31,28 @@ type ukkonen struct {
 	builder         Builder
 	traverser       Traverser
 	idFactory       *idFactory
-	debugChannel    chan string
+	tracer          Tracer
+	numberLeaves    int32
+	leafAdded       func(leaf Node)
 }
 
 func (b *ukkonen) NumberValuesLoaded() int32 {
 	return b.offset
 }
 
+// Debug sends each construction Event to dChan as a line of text, see EventString.
+// The channel must be read while the tree is built, Trace does not need a reader.
 func (b *ukkonen) Debug(dChan chan string) {
-	b.debugChannel = dChan
+	if dChan == nil {
+		b.tracer = nil
+		return
+	}
+	b.tracer = channelTracer(dChan)
+}
+
+// Trace delivers an Event to tracer for each step of the construction, nil stops tracing
+func (b *ukkonen) Trace(tracer Tracer) {
+	b.tracer = tracer
 }
 
 func (b *ukkonen) DataSource() DataSource {
@@ -53,14 +69,33 @@ func NewUkkonen(dataSource DataSource) Ukkonen {
 	root := suffixTree.Root()
 	return &ukkonen{dataSource.STKeys(), 0, NewLocation(root), root,
 		suffixTree, dataSource, nil,
-		NewBuilder(nodeIdFactory, dataSource), NewTraverser(dataSource), nodeIdFactory, nil}
+		NewBuilder(nodeIdFactory, dataSource), NewTraverser(dataSource), nodeIdFactory, nil, 0, nil}
 }
 
 func (b *ukkonen) DrainDataSource() {
 	var wg sync.WaitGroup
 	tickChannel := make(chan struct{}, 1)
-	b.DrainDataSourceWithTicks(&wg,tickChannel)
+	b.DrainDataSourceWithTicks(&wg, tickChannel)
 }
+
+// DrainDataSourceContext reads everything from the data source, returning once the channel is
+// closed, or with ctx.Err() if the context ends first.  The data source's goroutine is stopped
+// when the read is cancelled.  Build does the same, with progress reporting.
+func (b *ukkonen) DrainDataSourceContext(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			stopStream(b.dataSource)
+			return ctx.Err()
+		case value, ok := <-b.dataChannel:
+			if !ok {
+				return sourceErr(b.dataSource)
+			}
+			b.extendValue(value)
+		}
+	}
+}
+
 //
 // Read everything from the data source, when done signal the waitgroup, and close the tickChannel
 //
@@ -81,82 +116,107 @@ func (b *ukkonen) Extend() bool {
 	if !ok {
 		return false
 	}
+	b.extendValue(value)
+	return true
+}
 
+// extend the suffix tree with a value already read from the data channel
+func (b *ukkonen) extendValue(value STKey) {
 	// increment the offset after each successful read
 	defer func(b *ukkonen) {
 		b.offset++
 	}(b)
 
-	// otherwise, extend until done
-	if b.debugChannel != nil {
-		b.debugChannel <- fmt.Sprintf("Extend with value '%s' at %s", string(value), b.location)
+	b.finish(value)
+}
+
+// leaves are created in suffix order, so the count is also the offset of the longest implicit suffix
+func (b *ukkonen) addedLeaf(leaf Node) {
+	b.numberLeaves++
+	if b.leafAdded != nil {
+		b.leafAdded(leaf)
 	}
+}
 
-	b.finish(value)
-	if b.debugChannel != nil {
-		b.debugChannel <- fmt.Sprintf("Done with extension for '%s'", string(value))
+// number of nodes created so far, including the root
+func (b *ukkonen) numberNodes() int32 {
+	return atomic.LoadInt32(&b.idFactory._id)
+}
+
+func (b *ukkonen) traceLocationMoved(cause MoveCause) {
+	if b.tracer != nil {
+		b.tracer.Trace(LocationMoved{b.offset, cause, locationState(b.location)})
 	}
-	return true
 }
 
-func (b *ukkonen) prepareForNextExtension() {
-	if b.debugChannel != nil {
-		b.debugChannel <- fmt.Sprintf(" prepareForNextExtension starting at %s", b.location)
+func (b *ukkonen) setSuffixLink(from, to Node) {
+	from.SetSuffixLink(to)
+	if b.tracer != nil {
+		b.tracer.Trace(SuffixLinkSet{b.offset, from.Id(), to.Id()})
 	}
-	b.traverser.traverseToNextSuffix(b.location, b.debugChannel)
-	if b.debugChannel != nil {
-		b.debugChannel <- fmt.Sprintf(" .. after traverseToNextSuffix %s", b.location)
+}
+
+func (b *ukkonen) addedLeafEdge(rule ExtensionRule, edge *Edge, leaf Node) {
+	b.addedLeaf(leaf)
+	if b.tracer != nil {
+		b.tracer.Trace(LeafCreated{b.offset, rule, leaf.Id(), leaf.parent().Id(), leaf.SuffixOffset(), *edge})
 	}
+}
+
+func (b *ukkonen) prepareForNextExtension() {
+	b.traverser.traverseToNextSuffix(b.location)
+	b.traceLocationMoved(MoveNextSuffix)
 	// if we are on the root, and there's a node needing a suffix link, set it
 	// if it's not the root, we will be creating it here, and have to set it
 	// after it gets created
 	if b.location.Base.isRoot() && b.needsSuffixLink != nil {
-		b.needsSuffixLink.SetSuffixLink(b.location.Base)
+		b.setSuffixLink(b.needsSuffixLink, b.location.Base)
 		b.needsSuffixLink = nil
 	}
 }
 
 func (b *ukkonen) finish(value STKey) {
+	if b.tracer != nil {
+		b.tracer.Trace(ExtendStarted{b.offset, value, locationState(b.location)})
+	}
 	for b.extendWithValue(value) {
 		b.prepareForNextExtension()
 	}
 }
 
-func (b *ukkonen) Finish() {
-	b.finish(STKey('$'))
+// Terminator is the value Finish appends, so that every suffix ends at a leaf
+const Terminator STKey = '$'
+
+// Finish extends the tree with the Terminator and links the leaves, and returns the first error reported
+// by the data source (if the source failed mid-stream, the tree holds only the values read before the failure)
+func (b *ukkonen) Finish() error {
+	length := b.offset
+	b.finish(Terminator)
+	linkLeaves(b.root, b.dataSource, length)
+	return sourceErr(b.dataSource)
 }
 
 func (b *ukkonen) extendWithValue(value STKey) bool {
 	if b.location.OnNode {
-		if b.debugChannel != nil {
-			b.debugChannel <- fmt.Sprintf(" extendWithValue on Node %d", b.location.Base.Id())
-		}
 		// if the previous node needs a suffix link, this is the place
 		if b.needsSuffixLink != nil {
-			b.needsSuffixLink.SetSuffixLink(b.location.Base)
+			b.setSuffixLink(b.needsSuffixLink, b.location.Base)
 			b.needsSuffixLink = nil
 		}
 		// if child value is there, just update location
 		if b.location.Base.EdgeFollowing(value) != nil {
-			if b.debugChannel != nil {
-				b.debugChannel <- fmt.Sprintf("   node has an edge with that value, nothing to do")
-			}
 			b.traverser.traverseOne(b.location, value)
+			b.traceLocationMoved(MoveValuePresent)
 			return false
 		} else {
 			// otherwise we add the value
 			edge, node := b.location.Base.addLeafEdgeNode(b.idFactory.NextId(), value, b.offset)
-			if b.debugChannel != nil {
-				b.debugChannel <- fmt.Sprintf("   creating leaf edge, new node is %d, edge %s", node.Id(), edge)
-			}
+			b.addedLeafEdge(ExtensionNewLeaf, edge, node)
 			b.location.OnNode = true
 			return !b.location.Base.isRoot()
 		}
 
 	} else {
-		if b.debugChannel != nil {
-			b.debugChannel <- fmt.Sprintf(" extendWithValue on Edge, offset %d ending at Node %d", b.location.OffsetFromTop, b.location.Base.Id())
-		}
 		// we are on the edge, see if the character at the offset matches
 		valueOffset := b.location.Edge.StartOffset + b.location.OffsetFromTop
 		if b.dataSource.KeyAtOffset(valueOffset) == value {
@@ -165,31 +225,37 @@ func (b *ukkonen) extendWithValue(value STKey) bool {
 			if b.location.OffsetFromTop == b.location.Edge.length() {
 				b.location.OnNode = true
 			}
+			b.traceLocationMoved(MoveValuePresent)
 			return false
 		} else if b.location.Base.isRoot() {
 			// add leaf, set location
 			leafEdge, leafNode := NewLeafEdgeNode(b.idFactory.NextId(), b.root, b.offset)
 			b.location.Base.AddOutgoingEdgeNode(value, leafEdge, leafNode)
+			b.addedLeafEdge(ExtensionNewLeaf, leafEdge, leafNode)
 			b.location.Base = leafNode
 			b.location.OffsetFromTop = 0
 		} else {
 			// Rule 2
 			// - split the edge, and let builder know the new Node needs a suffix link on the next extension
 			previousNeedsSuffixLink := b.needsSuffixLink
-			b.needsSuffixLink = b.builder.split(b.location.Base.parent(), b.location.Base, b.location.Edge, b.location.OffsetFromTop)
-			if previousNeedsSuffixLink != nil {
-				previousNeedsSuffixLink.SetSuffixLink(b.needsSuffixLink)
+			child := b.location.Base
+			b.needsSuffixLink = b.builder.split(child.parent(), child, b.location.Edge, b.location.OffsetFromTop)
+			if b.tracer != nil {
+				b.tracer.Trace(EdgeSplit{b.offset, b.needsSuffixLink.Id(), b.needsSuffixLink.parent().Id(), child.Id(),
+					*b.needsSuffixLink.IncomingEdge(), *child.IncomingEdge()})
 			}
-			if b.debugChannel != nil {
-				b.debugChannel <- fmt.Sprintf(" extendWithValue split edge, creating Node %d", b.needsSuffixLink.Id())
+			if previousNeedsSuffixLink != nil {
+				b.setSuffixLink(previousNeedsSuffixLink, b.needsSuffixLink)
 			}
 			// - add the new leaf node
 			leafEdge, leafNode := NewLeafEdgeNode(b.idFactory.NextId(), b.needsSuffixLink, b.offset)
 			b.needsSuffixLink.AddOutgoingEdgeNode(value, leafEdge, leafNode)
+			b.addedLeafEdge(ExtensionSplitEdge, leafEdge, leafNode)
 
 			// after the split, we are located on the internal node
 			b.location.Base = b.needsSuffixLink
 			b.location.OnNode = true
+			b.traceLocationMoved(MoveSplitNode)
 
 			// - return true so we continue extending
 			return true
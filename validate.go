@@ -0,0 +1,195 @@
+package suffixtree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A Rule names one of the invariants Validate checks
+type Rule string
+
+const (
+	RuleChildMaps   Rule = "child-maps"   // a node's edge and child maps have the same keys, and no nil entries
+	RuleParent      Rule = "parent"       // a child's parent and incoming edge match its parent's maps
+	RuleEdgeKey     Rule = "edge-key"     // the first value of an edge is its key in the parent's maps
+	RuleBranching   Rule = "branching"    // an internal node has at least two children
+	RuleLeafOffsets Rule = "leaf-offsets" // the leaves hold each suffix offset exactly once, unless the suffix is implicit
+	RuleSuffixLink  Rule = "suffix-link"  // a suffix link leads to a node one value shallower
+	RuleFindable    Rule = "findable"     // a Searcher finds every suffix at its own offset
+)
+
+// A Violation is a broken invariant, with the node it was found at
+type Violation struct {
+	Rule    Rule
+	NodeId  int32
+	Path    string // the values from the root to the node
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: node %d at %q: %s", v.Rule, v.NodeId, v.Path, v.Message)
+}
+
+// Validate checks every invariant of a finished tree and returns all the violations found.
+//
+// Searching for every suffix makes Validate quadratic in the length of the data, it is meant for
+// tests and debugging rather than for large trees.
+func Validate(tree SuffixTree) []Violation {
+	v := &validator{tree: tree, dataSource: tree.DataSource(), depths: make(map[Node]int32), hasLeaf: make(map[int32]bool)}
+	v.checkNode(tree.Root(), 0)
+	v.length = int32(len(v.leaves)) - 1
+	if sized, ok := v.dataSource.(lengthKnown); ok {
+		v.length = int32(sized.Len())
+	}
+	v.checkSuffixLinks()
+	v.checkLeafOffsets()
+	sort.SliceStable(v.violations, func(i, j int) bool { return v.violations[i].NodeId < v.violations[j].NodeId })
+	return v.violations
+}
+
+type validator struct {
+	tree       SuffixTree
+	dataSource DataSource
+	depths     map[Node]int32
+	leaves     []Node
+	hasLeaf    map[int32]bool
+	length     int32 // the offset of the Terminator
+	violations []Violation
+}
+
+func (v *validator) violation(rule Rule, node Node, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{rule, node.Id(), pathToNode(node, v.dataSource), fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) checkNode(node Node, depth int32) {
+	v.depths[node] = depth
+	edges, nodes := node.OutgoingEdgeMap(), node.outgoingNodeMap()
+	for key := range edges {
+		if _, ok := nodes[key]; !ok {
+			v.violation(RuleChildMaps, node, "edge for value %d has no child node", key)
+		}
+	}
+	for key, child := range nodes {
+		edge, ok := edges[key]
+		if !ok {
+			v.violation(RuleChildMaps, node, "child node for value %d has no edge", key)
+			continue
+		}
+		if child == nil || edge == nil {
+			v.violation(RuleChildMaps, node, "nil edge or child for value %d", key)
+			continue
+		}
+		if child.parent() != node || child.IncomingEdge() != edge {
+			v.violation(RuleParent, child, "parent or incoming edge differs from node %d's child for value %d", node.Id(), key)
+		}
+		if first := v.dataSource.KeyAtOffset(edge.StartOffset); first != key {
+			v.violation(RuleEdgeKey, child, "edge %s starts with value %d but is the child for value %d", edge, first, key)
+		}
+		if child.IsLeaf() {
+			// leaves are not branching points, they are kept with their parent's depth
+			v.depths[child] = depth
+			v.leaves = append(v.leaves, child)
+			v.hasLeaf[child.SuffixOffset()] = true
+			if edge.StartOffset-depth != child.SuffixOffset() {
+				v.violation(RuleLeafOffsets, child, "leaf edge starts at %d below depth %d, but the leaf's suffix is %d",
+					edge.StartOffset, depth, child.SuffixOffset())
+			}
+		} else {
+			v.checkNode(child, depth+edge.length())
+		}
+	}
+	if node.isInternal() && len(nodes) < 2 {
+		v.violation(RuleBranching, node, "internal node has %d children", len(nodes))
+	}
+}
+
+func (v *validator) checkSuffixLinks() {
+	for node, depth := range v.depths {
+		link := node.SuffixLink()
+		switch {
+		case node.isRoot():
+			if link != node {
+				v.violation(RuleSuffixLink, node, "root does not link to itself")
+			}
+		case node.IsLeaf():
+			next := node.SuffixOffset() + 1
+			if v.hasLeaf[next] {
+				if link == nil || !link.IsLeaf() || link.SuffixOffset() != next {
+					v.violation(RuleSuffixLink, node, "leaf for suffix %d does not link to the leaf for suffix %d",
+						node.SuffixOffset(), next)
+				}
+			} else if want := deepestNodeOn(v.tree.Root(), v.dataSource, next, v.length); link != want {
+				// the next suffix is the empty one or has no leaf (see linkLeaves)
+				v.violation(RuleSuffixLink, node, "leaf for suffix %d does not link to node %d, the deepest on suffix %d",
+					node.SuffixOffset(), want.Id(), next)
+			}
+		default:
+			linkDepth, ok := v.depths[link]
+			if link == nil || !ok || link.IsLeaf() {
+				v.violation(RuleSuffixLink, node, "internal node has no suffix link to a branching node in the tree")
+			} else if linkDepth != depth-1 {
+				v.violation(RuleSuffixLink, node, "internal node at depth %d links to node %d at depth %d",
+					depth, link.Id(), linkDepth)
+			}
+		}
+	}
+}
+
+func (v *validator) checkLeafOffsets() {
+	searcher := NewSearcher(v.tree.Root(), v.dataSource)
+	n := v.length + 1
+	seen := make([]bool, n)
+	for _, leaf := range v.leaves {
+		suffix := leaf.SuffixOffset()
+		switch {
+		case suffix < 0 || suffix >= n:
+			v.violation(RuleLeafOffsets, leaf, "suffix %d is not between 0 and %d", suffix, n-1)
+		case seen[suffix]:
+			v.violation(RuleLeafOffsets, leaf, "suffix %d has more than one leaf", suffix)
+		default:
+			seen[suffix] = true
+			// the Terminator is not searched for, so the suffix may also occur earlier
+			sequence := v.suffix(suffix)
+			found, err := searcher.Find(sequence)
+			if err != nil {
+				v.violation(RuleFindable, leaf, "searching for suffix %d: %v", suffix, err)
+			} else if i := sort.Search(len(found), func(i int) bool { return found[i] >= suffix }); i == len(found) || found[i] != suffix {
+				v.violation(RuleFindable, leaf, "searching for suffix %d found %v", suffix, found)
+			}
+		}
+	}
+	missing := []int32{}
+	for suffix, ok := range seen {
+		if !ok && !v.implicit(searcher, int32(suffix)) {
+			missing = append(missing, int32(suffix))
+		}
+	}
+	if len(missing) > 0 {
+		v.violation(RuleLeafOffsets, v.tree.Root(), "no leaf for suffixes %v", missing)
+	}
+}
+
+// the values of a suffix, not including the Terminator
+func (v *validator) suffix(offset int32) []STKey {
+	values := make([]STKey, 0, v.length-offset)
+	for ; offset < v.length; offset++ {
+		values = append(values, v.dataSource.KeyAtOffset(offset))
+	}
+	return values
+}
+
+// whether a suffix followed by the Terminator also occurs earlier, followed by a '$' in the data.
+// Such a suffix ends inside an edge and has no leaf.
+func (v *validator) implicit(searcher Searcher, suffix int32) bool {
+	found, err := searcher.Find(v.suffix(suffix))
+	if err != nil {
+		return false
+	}
+	for _, offset := range found {
+		end := offset + v.length - suffix
+		if offset < suffix && end < v.length && v.dataSource.KeyAtOffset(end) == Terminator {
+			return true
+		}
+	}
+	return false
+}
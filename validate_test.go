@@ -0,0 +1,38 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestValidateBuilds(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	alphabets := []string{"abc", "ab$", "$"}
+	algorithms := []Algorithm{UkkonenAlgorithm, McCreightAlgorithm, SuffixArrayAlgorithm, ParallelAlgorithm}
+	for i := 0; i < 300; i++ {
+		s := randomString(r, r.Intn(40), alphabets[i%len(alphabets)])
+		for _, algorithm := range algorithms {
+			if violations := Validate(buildWith(t, s, algorithm)); len(violations) > 0 {
+				t.Fatalf("%q %s: %v", s, algorithm, violations)
+			}
+		}
+	}
+	if violations := Validate(buildString(t, "abb$abbbb")); len(violations) > 0 {
+		t.Errorf("\"abb$abbbb\": %v", violations)
+	}
+}
+
+func TestValidateReportsBrokenTree(t *testing.T) {
+	tree := buildString(t, "abcabx")
+	tree.Root().NodeFollowing('a').SetSuffixLink(tree.Root())
+	tree.Root().NodeFollowing('x').(*leafNode).setSuffixOffset(0)
+	found := make(map[Rule]bool)
+	for _, violation := range Validate(tree) {
+		found[violation.Rule] = true
+	}
+	for _, rule := range []Rule{RuleSuffixLink, RuleLeafOffsets} {
+		if !found[rule] {
+			t.Errorf("no %s violation reported", rule)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package suffixtree
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The saved index, the delta and the LZ77 formats are each a four byte magic followed by varints
+// and raw bytes.
+
+type varintWriter struct {
+	*bufio.Writer
+	buffer []byte
+}
+
+// start writing a format, with its magic
+func newVarintWriter(w io.Writer, magic string) *varintWriter {
+	out := &varintWriter{bufio.NewWriter(w), make([]byte, binary.MaxVarintLen64)}
+	out.WriteString(magic)
+	return out
+}
+
+func (out *varintWriter) uvarint(x uint64) {
+	out.Write(out.buffer[:binary.PutUvarint(out.buffer, x)])
+}
+
+func (out *varintWriter) varint(x int64) {
+	out.Write(out.buffer[:binary.PutVarint(out.buffer, x)])
+}
+
+// reads a format a varintWriter wrote, a failed read is reported as the format's error
+type varintReader struct {
+	*bufio.Reader
+	errBad error
+}
+
+// start reading a format, checking its magic
+func newVarintReader(r io.Reader, magic string, errBad error) (*varintReader, error) {
+	in := &varintReader{bufio.NewReader(r), errBad}
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(in, header); err != nil || string(header) != magic {
+		return nil, errBad
+	}
+	return in, nil
+}
+
+func (in *varintReader) bad(err error) error {
+	return fmt.Errorf("%w: %v", in.errBad, err)
+}
+
+func (in *varintReader) uvarint() (uint64, error) {
+	x, err := binary.ReadUvarint(in)
+	if err != nil {
+		return 0, in.bad(err)
+	}
+	return x, nil
+}
+
+func (in *varintReader) varint() (int64, error) {
+	x, err := binary.ReadVarint(in)
+	if err != nil {
+		return 0, in.bad(err)
+	}
+	return x, nil
+}
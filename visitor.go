@@ -158,8 +158,12 @@ func (dv *DepthVisitor) Visit(node Node) bool {
 	return true
 }
 
-func (dv* DepthVisitor) Finish() {
-	fmt.Printf("Depth Visitor has completed\n")
-	fmt.Printf("  depth=%d\n", dv.maxDepth)
-	fmt.Printf("  nodes emitting values=%d\n", dv.numberOfNodesEmittingValues)
+// Finish does nothing, the visitor's figures are read with NodesEmittingValues.
+// KmerCounts reports each k-mer with its count rather than its set of suffixes.
+func (dv *DepthVisitor) Finish() {
+}
+
+// NodesEmittingValues is the number of suffix sets sent so far
+func (dv *DepthVisitor) NodesEmittingValues() int32 {
+	return dv.numberOfNodesEmittingValues
 }
\ No newline at end of file
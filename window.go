@@ -0,0 +1,264 @@
+package suffixtree
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// SlidingWindow keeps the suffix tree of the most recent values of an unbounded stream.
+//
+// Once the window is full, each new value first deletes the oldest suffix (Larsson's sliding
+// window construction), so memory stays proportional to the window size and Find only reports
+// offsets inside the window.  Offsets count from the start of the stream, so a window can
+// follow at most 2^31-1 values.
+type SlidingWindow struct {
+	builder    *ukkonen
+	dataSource *windowDataSource
+	leaves     map[int32]Node
+}
+
+// NewSlidingWindow keeps the suffix tree of the last size values read from the data source
+func NewSlidingWindow(dataSource DataSource, size int32) (*SlidingWindow, error) {
+	if size < 1 {
+		return nil, errors.New("suffixtree: a sliding window must hold at least one value")
+	}
+	windowSource := &windowDataSource{source: dataSource, values: make([]STKey, size)}
+	w := &SlidingWindow{NewUkkonen(windowSource).(*ukkonen), windowSource, make(map[int32]Node)}
+	w.builder.leafAdded = func(leaf Node) {
+		w.leaves[leaf.SuffixOffset()] = leaf
+	}
+	return w, nil
+}
+
+// Extend adds the next value, deleting the oldest one if the window is full.
+// It returns false once the data channel is closed.
+func (w *SlidingWindow) Extend() bool {
+	value, ok := <-w.dataSource.source.STKeys()
+	if !ok {
+		return false
+	}
+	if w.dataSource.stored-w.dataSource.tail == int32(len(w.dataSource.values)) {
+		w.deleteOldest()
+	}
+	w.dataSource.store(value)
+	w.builder.extendValue(value)
+	return true
+}
+
+// Start is the offset of the oldest value in the window
+func (w *SlidingWindow) Start() int32 {
+	return w.dataSource.tail
+}
+
+// End is the offset following the newest value in the window
+func (w *SlidingWindow) End() int32 {
+	return w.dataSource.stored
+}
+
+func (w *SlidingWindow) Tree() SuffixTree {
+	return w.builder.Tree()
+}
+
+// Find returns the offsets inside the window where the sequence occurs
+func (w *SlidingWindow) Find(sequence []STKey) ([]int32, error) {
+	return w.builder.findBefore(sequence, w.dataSource.stored)
+}
+
+// remove the leaf of the oldest suffix.  Edge labels are kept inside the window by reading
+// every edge from a suffix in the subtree below it, when that suffix is deleted the edges
+// read from it are relabelled from another suffix in the same subtree.
+func (w *SlidingWindow) deleteOldest() {
+	b := w.builder
+	tail := w.dataSource.tail
+	leaf := w.leaves[tail]
+	delete(w.leaves, tail)
+	leaf.SetSuffixLink(nil)
+
+	// ancestors of the leaf, nearest first, and the depth of each
+	path := []Node{}
+	for node := leaf.parent(); node != nil; node = node.parent() {
+		path = append(path, node)
+	}
+	depths := make([]int32, len(path))
+	for i := len(path) - 2; i >= 0; i-- {
+		depths[i] = depths[i+1] + path[i].IncomingEdge().length()
+	}
+
+	var replacement int32
+	if !b.location.OnNode && b.location.Base == leaf {
+		// the active point is on the leaf's edge, so the longest implicit suffix only occurred
+		// as a prefix of the deleted suffix: it takes over the leaf, and the active point moves on
+		replacement = b.numberLeaves
+		leaf.(*leafNode).setSuffixOffset(replacement)
+		leaf.IncomingEdge().StartOffset = replacement + depths[0]
+		w.leaves[replacement] = leaf
+		b.numberLeaves++
+		w.relabel(path, depths, tail, replacement)
+		b.prepareForNextExtension()
+	} else {
+		parent := path[0]
+		parent.removeEdgeFollowing(childKey(parent, leaf))
+		replacement = anySuffixBelow(parent)
+		if parent.isInternal() && parent.NumberOutgoing() == 1 {
+			w.merge(parent)
+			path, depths = path[1:], depths[1:]
+		}
+		w.relabel(path, depths, tail, replacement)
+	}
+	w.dataSource.tail++
+}
+
+// relabel the incoming edges of nodes on path that were read from the deleted suffix
+func (w *SlidingWindow) relabel(path []Node, depths []int32, deleted, replacement int32) {
+	for i, node := range path {
+		if node.isRoot() {
+			return
+		}
+		edge := node.IncomingEdge()
+		parentDepth := depths[i+1]
+		if edge.StartOffset-parentDepth == deleted {
+			length := edge.length()
+			edge.StartOffset = replacement + parentDepth
+			edge.EndOffset = edge.StartOffset + length - 1
+		}
+	}
+}
+
+// remove an internal node left with a single child, joining its incoming edge to the child's
+func (w *SlidingWindow) merge(node Node) {
+	var child Node
+	for _, child = range node.outgoingNodeMap() {
+	}
+	parent := node.parent()
+	topLength := node.IncomingEdge().length()
+	key := childKey(parent, node)
+	// the node leaves the tree, so it no longer links into it
+	node.SetSuffixLink(nil)
+
+	// the child keeps its Edge, so a Location on that edge stays valid once its offset is adjusted
+	edge := child.IncomingEdge()
+	edge.StartOffset -= topLength
+	parent.AddOutgoingEdgeNode(key, edge, child)
+	child.setIncoming(parent, edge)
+
+	location := w.builder.location
+	switch location.Base {
+	case node:
+		if location.OnNode {
+			location.OffsetFromTop = topLength
+		}
+		location.Base = child
+		location.Edge = edge
+		location.OnNode = false
+	case child:
+		if !location.OnNode {
+			location.OffsetFromTop += topLength
+		}
+	}
+}
+
+// the key a parent uses for one of its children
+func childKey(parent, child Node) STKey {
+	for key, node := range parent.outgoingNodeMap() {
+		if node == child {
+			return key
+		}
+	}
+	panic(fmt.Sprintf("node %d is not a child of node %d", child.Id(), parent.Id()))
+}
+
+// the suffix offset of some leaf at or below node, UnspecifiedOffset if there is none
+func anySuffixBelow(node Node) int32 {
+	for !node.IsLeaf() {
+		var child Node
+		for _, child = range node.outgoingNodeMap() {
+			break
+		}
+		if child == nil {
+			return UnspecifiedOffset
+		}
+		node = child
+	}
+	return node.SuffixOffset()
+}
+
+// outOfWindow is returned for offsets that have not been read yet, it matches no value
+const outOfWindow STKey = math.MinInt32
+
+// windowDataSource holds the values of a SlidingWindow in a ring
+type windowDataSource struct {
+	firstError
+	source DataSource
+	values []STKey
+	tail   int32 // offset of the oldest value held
+	stored int32 // number of values read from the source
+}
+
+func (ws *windowDataSource) store(value STKey) {
+	ws.values[ws.stored%int32(len(ws.values))] = value
+	ws.stored++
+}
+
+func (ws *windowDataSource) KeyAtOffset(offset int32) STKey {
+	value, err := ws.keyAt(offset)
+	if err != nil {
+		ws.setErr(err)
+	}
+	return value
+}
+
+func (ws *windowDataSource) keyAt(offset int32) (STKey, error) {
+	if offset >= ws.stored {
+		return outOfWindow, nil
+	}
+	if offset < ws.tail {
+		return outOfWindow, fmt.Errorf("%w: %d is before the window starting at %d", ErrOffsetOutOfRange, offset, ws.tail)
+	}
+	return ws.values[offset%int32(len(ws.values))], nil
+}
+
+func (ws *windowDataSource) STKeys() <-chan STKey {
+	return ws.source.STKeys()
+}
+
+func (ws *windowDataSource) stopStream() {
+	stopStream(ws.source)
+}
+
+func (ws *windowDataSource) byteValues() bool {
+	return holdsBytes(ws.source)
+}
+
+func (ws *windowDataSource) StringFrom(start, end int32) string {
+	x := ""
+	if end < 0 {
+		end = start
+		x = "..."
+	}
+	result := []rune{}
+	for ; start <= end; start++ {
+		result = append(result, rune(ws.KeyAtOffset(start)))
+	}
+	return string(result) + x
+}
+
+func (ws *windowDataSource) StringFromTo(start int32, end string) string {
+	endRunes := []rune(end)
+	result := []rune{}
+	for ; start < ws.stored; start++ {
+		value := ws.KeyAtOffset(start)
+		if len(endRunes) > 0 && rune(value) == endRunes[0] {
+			break
+		}
+		result = append(result, rune(value))
+	}
+	return string(result)
+}
+
+func (ws *windowDataSource) Err() error {
+	if err := ws.firstError.Err(); err != nil {
+		return err
+	}
+	return sourceErr(ws.source)
+}
@@ -0,0 +1,101 @@
+package suffixtree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the values on the path from the root to node
+func pathTo(node Node, dataSource DataSource) []STKey {
+	edges := []*Edge{}
+	for ; !node.isRoot(); node = node.parent() {
+		edges = append(edges, node.IncomingEdge())
+	}
+	path := []STKey{}
+	for i := len(edges) - 1; i >= 0; i-- {
+		for offset := edges[i].StartOffset; offset <= edges[i].EndOffset; offset++ {
+			path = append(path, dataSource.KeyAtOffset(offset))
+		}
+	}
+	return path
+}
+
+// every internal node in the window's tree links to a node in the tree, the one for its path
+// without the first value
+func checkWindowLinks(t *testing.T, w *SlidingWindow) {
+	t.Helper()
+	inTree := map[Node]bool{}
+	internal := []Node{}
+	for nodes := []Node{w.Tree().Root()}; len(nodes) > 0; {
+		node := nodes[len(nodes)-1]
+		nodes = nodes[:len(nodes)-1]
+		inTree[node] = true
+		if node.isInternal() {
+			internal = append(internal, node)
+		}
+		nodes = append(nodes, node.OutgoingNodes()...)
+	}
+	for _, node := range internal {
+		link := node.SuffixLink()
+		if link == nil {
+			continue
+		}
+		if !inTree[link] {
+			t.Fatalf("window [%d,%d): node %d links to node %d, which was deleted", w.Start(), w.End(), node.Id(), link.Id())
+		}
+		path, linkPath := pathTo(node, w.dataSource), pathTo(link, w.dataSource)
+		if keysString(path[1:]) != keysString(linkPath) {
+			t.Fatalf("window [%d,%d): node %d for %q links to node %d for %q", w.Start(), w.End(), node.Id(),
+				keysString(path), link.Id(), keysString(linkPath))
+		}
+	}
+}
+
+func keysString(keys []STKey) string {
+	values := make([]rune, len(keys))
+	for i, key := range keys {
+		values[i] = rune(key)
+	}
+	return string(values)
+}
+
+// at every position of the window, Find reports the occurrences inside it
+func TestSlidingWindowFind(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		alphabet := []string{"a", "ab", "abc", "aab"}[i%4]
+		s := randomString(r, 1+r.Intn(200), alphabet)
+		size := int32(1 + r.Intn(20))
+		w, err := NewSlidingWindow(NewStringDataSource(s), size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for w.Extend() {
+			start, end := w.Start(), w.End()
+			if end-start > size || end-start < 1 {
+				t.Fatalf("%q: window [%d,%d) for size %d", s, start, end, size)
+			}
+			for q := 0; q < 5; q++ {
+				pattern := randomString(r, 1+r.Intn(4), alphabet)
+				found, err := w.Find(stringKeys(pattern))
+				want := occurrencesIn(s[start:end], pattern)
+				for j := range want {
+					want[j] += start
+				}
+				if err != nil || !sameOffsets(found, want) {
+					t.Fatalf("%q size %d, window [%d,%d): Find(%q) = %v, %v, want %v", s, size, start, end, pattern, found, err, want)
+				}
+			}
+			checkWindowLinks(t, w)
+		}
+		if err := sourceErr(w.dataSource); err != nil {
+			t.Fatalf("%q size %d: %v", s, size, err)
+		}
+	}
+}
+
+func TestSlidingWindowSize(t *testing.T) {
+	if _, err := NewSlidingWindow(NewStringDataSource("abc"), 0); err == nil {
+		t.Error("made a window holding no values")
+	}
+}